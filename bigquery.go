@@ -2,13 +2,14 @@ package bigquery
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
-	"regexp"
 	"strings"
 
+	"cloud.google.com/go/bigquery"
 	"github.com/basemachina/go-bigquery/adaptor"
-	_ "github.com/basemachina/go-bigquery/driver"
+	"github.com/basemachina/go-bigquery/driver"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
@@ -24,6 +25,10 @@ type Config struct {
 	DSN                  string
 	PreferSimpleProtocol bool
 	Conn                 *sql.DB
+	// Client, if set, is shared by every connection instead of creating a client from the DSN.
+	// The DSN is still used for the dataset and location; its credential parameters are ignored,
+	// and job_creation_mode is rejected as it is a setting of the client.
+	Client *bigquery.Client
 }
 
 func Open(dsn string) gorm.Dialector {
@@ -42,6 +47,8 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 
 	if dialector.Conn != nil {
 		db.ConnPool = dialector.Conn
+	} else if dialector.Client != nil {
+		db.ConnPool, err = dialector.openClient()
 	} else {
 		db.ConnPool, err = sql.Open("bigquery", dialector.Config.DSN)
 	}
@@ -49,6 +56,21 @@ func (dialector Dialector) Initialize(db *gorm.DB) (err error) {
 	return
 }
 
+func (dialector Dialector) openClient() (*sql.DB, error) {
+	var opts []driver.ConnectorOption
+	if dialector.DSN != "" {
+		config, err := driver.ParseDSN(dialector.DSN)
+		if err != nil {
+			return nil, err
+		}
+		if config.JobCreationMode != "" {
+			return nil, errors.New("job_creation_mode cannot be used with Config.Client; create the client with bigquery.WithDefaultJobCreationMode instead")
+		}
+		opts = append(opts, driver.WithConfig(config))
+	}
+	return sql.OpenDB(driver.NewConnector(dialector.Client, opts...)), nil
+}
+
 func (dialector Dialector) Migrator(db *gorm.DB) gorm.Migrator {
 	return Migrator{migrator.Migrator{Config: migrator.Config{
 		DB:                          db,
@@ -71,10 +93,8 @@ func (Dialector) QuoteTo(writer clause.Writer, str string) {
 	writer.WriteByte('`')
 }
 
-var numericPlaceholder = regexp.MustCompile("\\$(\\d+)")
-
 func (Dialector) Explain(sql string, vars ...interface{}) string {
-	return logger.ExplainSQL(sql, numericPlaceholder, `'`, vars...)
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
 }
 
 func (dialector Dialector) DataTypeOf(field *schema.Field) string {
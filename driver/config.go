@@ -0,0 +1,469 @@
+package driver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+// Config is a configuration parsed from a DSN string.
+// A DSN has the form "bigquery://project/dataset" or "bigquery://project/location/dataset",
+// optionally followed by query parameters.
+//
+// The project in the host is the one jobs are billed to. When the dataset belongs to
+// another project, it is written as "data-project.dataset", for example
+// "bigquery://billing-project/data-project.dataset".
+type Config struct {
+	ProjectID      string
+	DataProjectID  string
+	Location       string
+	DatasetID      string
+	Scopes         []string
+	Endpoint       string
+	DisableAuth    bool
+	CredentialFile string
+	CredentialJSON []byte
+	// CredentialProvider is the name of a provider registered with RegisterCredentialProvider.
+	CredentialProvider string
+
+	// ImpersonateServiceAccount, if set, wraps the configured credentials in
+	// credentials impersonating this service account.
+	ImpersonateServiceAccount string
+	Delegates                 []string
+	Lifetime                  time.Duration
+
+	// UserClientCacheSize bounds the number of clients kept for end-user credentials
+	// given through WithUserTokenSource. DefaultUserClientCacheSize is used when zero.
+	UserClientCacheSize int
+
+	// Job defaults applied to every query started by the connection.
+	MaxBytesBilled          int64
+	Priority                bigquery.QueryPriority
+	Labels                  map[string]string
+	DisableQueryCache       bool
+	JobTimeout              time.Duration
+	UseLegacySQL            bool
+	DisableFlattenedResults bool
+
+	// JobCreationMode set to bigquery.JobCreationModeOptional lets BigQuery run short queries
+	// without creating a job. Scripts and DML statements always create a job.
+	JobCreationMode bigquery.JobCreationMode
+
+	// TranslatePlaceholders rewrites the $n and :name placeholders of other SQL dialects
+	// to BigQuery's ? and @name, rearranging the arguments to match.
+	TranslatePlaceholders bool
+
+	// TempFunctions are the names of temporary functions registered with RegisterTempFunctions.
+	// Queries are run with the definitions of those they call.
+	TempFunctions []string
+
+	// ConnectionProperties are attached to every query. The supported properties are listed
+	// by SupportedConnectionProperties and are set in the DSN by their names, for example
+	// "time_zone=Asia/Tokyo".
+	ConnectionProperties map[string]string
+}
+
+var supportedConnectionProperties = []string{"time_zone", "query_label", "dataset_project_id"}
+
+// SupportedConnectionProperties returns the connection properties that can be set in the DSN
+// or with WithConnectionProperty:
+//
+//   - time_zone: the default time zone, for example "Asia/Tokyo"
+//   - query_label: a "key:value" label attached to the job
+//   - dataset_project_id: the default project for datasets referenced in the query
+func SupportedConnectionProperties() []string {
+	return slices.Clone(supportedConnectionProperties)
+}
+
+// ParseDSN parses the DSN string to a Config.
+// Unknown query parameters are rejected.
+func ParseDSN(dsn string) (*Config, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, invalidConnectionStringError(dsn)
+	}
+
+	if u.Scheme != "bigquery" {
+		return nil, fmt.Errorf("invalid prefix, expected bigquery:// got: %s", dsn)
+	}
+
+	if u.Path == "" {
+		return nil, invalidConnectionStringError(dsn)
+	}
+
+	fields := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
+	if len(fields) > 2 {
+		return nil, invalidConnectionStringError(dsn)
+	}
+
+	config := &Config{
+		ProjectID: u.Hostname(),
+		DatasetID: fields[len(fields)-1],
+	}
+	// Dataset IDs cannot contain dots while domain-scoped project IDs can,
+	// so the last dot separates the data project from the dataset.
+	if index := strings.LastIndex(config.DatasetID, "."); index != -1 {
+		config.DataProjectID = config.DatasetID[:index]
+		config.DatasetID = config.DatasetID[index+1:]
+		if config.DataProjectID == "" || config.DatasetID == "" {
+			return nil, invalidConnectionStringError(dsn)
+		}
+	}
+	if len(fields) == 2 {
+		config.Location = fields[0]
+	}
+
+	var unknown []string
+	for key, values := range u.Query() {
+		value := values[len(values)-1]
+		switch key {
+		case "scopes":
+			config.Scopes = getScopes(value)
+		case "endpoint":
+			config.Endpoint = value
+		case "disable_auth":
+			config.DisableAuth, err = parseBoolParameter(key, value)
+		case "credential_file":
+			config.CredentialFile = value
+		case "credential_json":
+			config.CredentialJSON, err = base64.StdEncoding.DecodeString(value)
+		case "credentials":
+			config.CredentialProvider = value
+		case "impersonate_service_account":
+			config.ImpersonateServiceAccount = value
+		case "delegates":
+			config.Delegates = splitList(value)
+		case "lifetime":
+			config.Lifetime, err = time.ParseDuration(value)
+			if err != nil {
+				err = invalidParameterError(key, value)
+			}
+		case "user_client_cache_size":
+			config.UserClientCacheSize, err = strconv.Atoi(value)
+			if err != nil || config.UserClientCacheSize < 0 {
+				err = invalidParameterError(key, value)
+			}
+		case "maximum_bytes_billed":
+			config.MaxBytesBilled, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				err = invalidParameterError(key, value)
+			}
+		case "priority":
+			config.Priority, err = parsePriority(value)
+		case "labels":
+			config.Labels, err = parseLabels(values)
+		case "use_query_cache":
+			var useQueryCache bool
+			useQueryCache, err = parseBoolParameter(key, value)
+			config.DisableQueryCache = !useQueryCache
+		case "job_timeout":
+			config.JobTimeout, err = time.ParseDuration(value)
+			if err != nil {
+				err = invalidParameterError(key, value)
+			}
+		case "use_legacy_sql":
+			config.UseLegacySQL, err = parseBoolParameter(key, value)
+		case "disable_flatten_results":
+			config.DisableFlattenedResults, err = parseBoolParameter(key, value)
+		case "job_creation_mode":
+			config.JobCreationMode, err = parseJobCreationMode(value)
+		case "translate_placeholders":
+			config.TranslatePlaceholders, err = parseBoolParameter(key, value)
+		case "temp_functions":
+			config.TempFunctions = splitList(value)
+		case "time_zone", "query_label", "dataset_project_id":
+			if config.ConnectionProperties == nil {
+				config.ConnectionProperties = make(map[string]string)
+			}
+			config.ConnectionProperties[key] = value
+		default:
+			unknown = append(unknown, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameters in connection string: %s", strings.Join(unknown, ", "))
+	}
+
+	if config.CredentialProvider != "" && (config.CredentialFile != "" || len(config.CredentialJSON) != 0) {
+		return nil, errors.New("credentials cannot be used with credential_file or credential_json")
+	}
+	if config.ImpersonateServiceAccount == "" && (len(config.Delegates) > 0 || config.Lifetime != 0) {
+		return nil, errors.New("delegates and lifetime require impersonate_service_account")
+	}
+	if config.ImpersonateServiceAccount != "" && config.DisableAuth {
+		return nil, errors.New("impersonate_service_account cannot be used with disable_auth")
+	}
+
+	return config, nil
+}
+
+// FormatDSN formats the Config as a DSN string that ParseDSN accepts.
+func (config *Config) FormatDSN() string {
+	path := "/" + config.DatasetID
+	if config.DataProjectID != "" {
+		path = "/" + config.DataProjectID + "." + config.DatasetID
+	}
+	if config.Location != "" {
+		path = "/" + config.Location + path
+	}
+
+	query := url.Values{}
+	if len(config.Scopes) > 0 {
+		query.Set("scopes", strings.Join(config.Scopes, ","))
+	}
+	if config.Endpoint != "" {
+		query.Set("endpoint", config.Endpoint)
+	}
+	if config.DisableAuth {
+		query.Set("disable_auth", "true")
+	}
+	if config.CredentialFile != "" {
+		query.Set("credential_file", config.CredentialFile)
+	}
+	if len(config.CredentialJSON) != 0 {
+		query.Set("credential_json", base64.StdEncoding.EncodeToString(config.CredentialJSON))
+	}
+	if config.CredentialProvider != "" {
+		query.Set("credentials", config.CredentialProvider)
+	}
+	if config.ImpersonateServiceAccount != "" {
+		query.Set("impersonate_service_account", config.ImpersonateServiceAccount)
+	}
+	if len(config.Delegates) > 0 {
+		query.Set("delegates", strings.Join(config.Delegates, ","))
+	}
+	if config.Lifetime != 0 {
+		query.Set("lifetime", config.Lifetime.String())
+	}
+	if config.UserClientCacheSize != 0 {
+		query.Set("user_client_cache_size", strconv.Itoa(config.UserClientCacheSize))
+	}
+	if config.MaxBytesBilled != 0 {
+		query.Set("maximum_bytes_billed", strconv.FormatInt(config.MaxBytesBilled, 10))
+	}
+	if config.Priority != "" {
+		query.Set("priority", strings.ToLower(string(config.Priority)))
+	}
+	keys := make([]string, 0, len(config.Labels))
+	for key := range config.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Add("labels", key+":"+config.Labels[key])
+	}
+	if config.DisableQueryCache {
+		query.Set("use_query_cache", "false")
+	}
+	if config.JobTimeout != 0 {
+		query.Set("job_timeout", config.JobTimeout.String())
+	}
+	if config.UseLegacySQL {
+		query.Set("use_legacy_sql", "true")
+	}
+	if config.DisableFlattenedResults {
+		query.Set("disable_flatten_results", "true")
+	}
+	if config.JobCreationMode != "" {
+		query.Set("job_creation_mode", strings.ToLower(strings.TrimPrefix(string(config.JobCreationMode), "JOB_CREATION_")))
+	}
+	if config.TranslatePlaceholders {
+		query.Set("translate_placeholders", "true")
+	}
+	if len(config.TempFunctions) > 0 {
+		query.Set("temp_functions", strings.Join(config.TempFunctions, ","))
+	}
+	for key, value := range config.ConnectionProperties {
+		query.Set(key, value)
+	}
+
+	u := url.URL{
+		Scheme:   "bigquery",
+		Host:     config.ProjectID,
+		Path:     path,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// dataProject returns the project that owns the dataset.
+func (config *Config) dataProject() string {
+	if config.DataProjectID != "" {
+		return config.DataProjectID
+	}
+	return config.ProjectID
+}
+
+func (config *Config) clientOptions(ctx context.Context) ([]option.ClientOption, error) {
+	opts := []option.ClientOption{option.WithScopes(config.Scopes...)}
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+	}
+	if config.DisableAuth {
+		opts = append(opts, option.WithoutAuthentication())
+	}
+	if config.JobCreationMode != "" {
+		opts = append(opts, bigquery.WithDefaultJobCreationMode(config.JobCreationMode))
+	}
+
+	credentials, err := config.credentialOptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.ImpersonateServiceAccount == "" {
+		return append(opts, credentials...), nil
+	}
+
+	tokenSource, err := impersonate.CredentialsTokenSource(ctx, config.impersonationConfig(), credentials...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to impersonate '%s': %w", config.ImpersonateServiceAccount, err)
+	}
+	return append(opts, option.WithTokenSource(tokenSource)), nil
+}
+
+// credentialOptions returns the base credentials configured in the DSN,
+// resolving the credential provider if any.
+// Application default credentials are used when none are configured.
+func (config *Config) credentialOptions(ctx context.Context) ([]option.ClientOption, error) {
+	var opts []option.ClientOption
+	if config.CredentialProvider != "" {
+		opt, err := resolveCredentialProvider(ctx, config.CredentialProvider)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+	if config.CredentialFile != "" {
+		opts = append(opts, option.WithCredentialsFile(config.CredentialFile))
+	}
+	if len(config.CredentialJSON) != 0 {
+		opts = append(opts, option.WithCredentialsJSON(config.CredentialJSON))
+	}
+	return opts, nil
+}
+
+func (config *Config) impersonationConfig() impersonate.CredentialsConfig {
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{bigquery.Scope}
+	}
+	return impersonate.CredentialsConfig{
+		TargetPrincipal: config.ImpersonateServiceAccount,
+		Scopes:          scopes,
+		Delegates:       config.Delegates,
+		Lifetime:        config.Lifetime,
+	}
+}
+
+// applyJobDefaults sets the job defaults of the configuration on the query.
+func (config *Config) applyJobDefaults(query *bigquery.Query) {
+	query.MaxBytesBilled = config.MaxBytesBilled
+	query.Priority = config.Priority
+	if len(config.Labels) > 0 {
+		query.Labels = make(map[string]string, len(config.Labels))
+		for key, value := range config.Labels {
+			query.Labels[key] = value
+		}
+	}
+	query.DisableQueryCache = config.DisableQueryCache
+	query.JobTimeout = config.JobTimeout
+	query.UseLegacySQL = config.UseLegacySQL
+	query.DisableFlattenedResults = config.DisableFlattenedResults
+	for _, key := range supportedConnectionProperties {
+		if value, ok := config.ConnectionProperties[key]; ok {
+			query.ConnectionProperties = append(query.ConnectionProperties, &bigquery.ConnectionProperty{Key: key, Value: value})
+		}
+	}
+}
+
+func validateConnectionProperties(properties []*bigquery.ConnectionProperty) error {
+	for _, property := range properties {
+		if !slices.Contains(supportedConnectionProperties, property.Key) {
+			return fmt.Errorf("unsupported connection property '%s', supported properties are: %s", property.Key, strings.Join(supportedConnectionProperties, ", "))
+		}
+	}
+	return nil
+}
+
+func getScopes(value string) []string {
+	q := strings.Trim(value, ",")
+	if q == "" {
+		return []string{}
+	}
+	return strings.Split(q, ",")
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func parseBoolParameter(key string, value string) (bool, error) {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, invalidParameterError(key, value)
+	}
+	return b, nil
+}
+
+func parsePriority(value string) (bigquery.QueryPriority, error) {
+	switch strings.ToLower(value) {
+	case "batch":
+		return bigquery.BatchPriority, nil
+	case "interactive":
+		return bigquery.InteractivePriority, nil
+	}
+	return "", invalidParameterError("priority", value)
+}
+
+func parseJobCreationMode(value string) (bigquery.JobCreationMode, error) {
+	switch strings.ToLower(value) {
+	case "optional":
+		return bigquery.JobCreationModeOptional, nil
+	case "required":
+		return bigquery.JobCreationModeRequired, nil
+	}
+	return "", invalidParameterError("job_creation_mode", value)
+}
+
+// parseLabels parses repeated "key:value" label parameters.
+func parseLabels(values []string) (map[string]string, error) {
+	labels := make(map[string]string, len(values))
+	for _, value := range values {
+		key, label, ok := strings.Cut(value, ":")
+		if !ok || key == "" {
+			return nil, invalidParameterError("labels", value)
+		}
+		labels[key] = label
+	}
+	return labels, nil
+}
+
+func invalidParameterError(key string, value string) error {
+	return fmt.Errorf("invalid value for parameter '%s': %s", key, value)
+}
+
+func invalidConnectionStringError(uri string) error {
+	return fmt.Errorf("invalid connection string: %s", uri)
+}
@@ -0,0 +1,341 @@
+package driver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
+)
+
+func TestParseDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		dsn     string
+		want    *Config
+		wantErr string
+	}{
+		"project and dataset": {
+			dsn:  "bigquery://project/dataset",
+			want: &Config{ProjectID: "project", DatasetID: "dataset"},
+		},
+		"project, location and dataset": {
+			dsn:  "bigquery://project/asia-northeast1/dataset",
+			want: &Config{ProjectID: "project", Location: "asia-northeast1", DatasetID: "dataset"},
+		},
+		"billing project and data project": {
+			dsn:  "bigquery://billing-project/data-project.dataset",
+			want: &Config{ProjectID: "billing-project", DataProjectID: "data-project", DatasetID: "dataset"},
+		},
+		"domain-scoped data project with location": {
+			dsn:  "bigquery://billing-project/US/example.com:data-project.dataset",
+			want: &Config{ProjectID: "billing-project", DataProjectID: "example.com:data-project", Location: "US", DatasetID: "dataset"},
+		},
+		"empty data project": {
+			dsn:     "bigquery://project/.dataset",
+			wantErr: "invalid connection string: bigquery://project/.dataset",
+		},
+		"all parameters": {
+			dsn: "bigquery://project/dataset?scopes=a,b&endpoint=http%3A%2F%2Flocalhost%3A9050&disable_auth=true&credential_file=%2Fpath%2Fkey.json&credential_json=e30%3D",
+			want: &Config{
+				ProjectID:      "project",
+				DatasetID:      "dataset",
+				Scopes:         []string{"a", "b"},
+				Endpoint:       "http://localhost:9050",
+				DisableAuth:    true,
+				CredentialFile: "/path/key.json",
+				CredentialJSON: []byte("{}"),
+			},
+		},
+		"job defaults": {
+			dsn: "bigquery://project/dataset?maximum_bytes_billed=1000000&priority=BATCH&labels=team:data&labels=env:prod&use_query_cache=false&job_timeout=30s&use_legacy_sql=true&disable_flatten_results=true",
+			want: &Config{
+				ProjectID:               "project",
+				DatasetID:               "dataset",
+				MaxBytesBilled:          1000000,
+				Priority:                bigquery.BatchPriority,
+				Labels:                  map[string]string{"team": "data", "env": "prod"},
+				DisableQueryCache:       true,
+				JobTimeout:              30 * time.Second,
+				UseLegacySQL:            true,
+				DisableFlattenedResults: true,
+			},
+		},
+		"impersonation": {
+			dsn: "bigquery://project/dataset?credential_file=%2Fpath%2Fkey.json&impersonate_service_account=tenant%40project.iam.gserviceaccount.com&delegates=a%40project.iam.gserviceaccount.com,b%40project.iam.gserviceaccount.com&lifetime=30m",
+			want: &Config{
+				ProjectID:                 "project",
+				DatasetID:                 "dataset",
+				CredentialFile:            "/path/key.json",
+				ImpersonateServiceAccount: "tenant@project.iam.gserviceaccount.com",
+				Delegates:                 []string{"a@project.iam.gserviceaccount.com", "b@project.iam.gserviceaccount.com"},
+				Lifetime:                  30 * time.Minute,
+			},
+		},
+		"credential provider": {
+			dsn:  "bigquery://project/dataset?credentials=vault-prod",
+			want: &Config{ProjectID: "project", DatasetID: "dataset", CredentialProvider: "vault-prod"},
+		},
+		"credential provider with credential file": {
+			dsn:     "bigquery://project/dataset?credentials=vault-prod&credential_file=%2Fpath%2Fkey.json",
+			wantErr: "credentials cannot be used with credential_file or credential_json",
+		},
+		"delegates without impersonation": {
+			dsn:     "bigquery://project/dataset?delegates=a%40project.iam.gserviceaccount.com",
+			wantErr: "delegates and lifetime require impersonate_service_account",
+		},
+		"impersonation without authentication": {
+			dsn:     "bigquery://project/dataset?disable_auth=true&impersonate_service_account=tenant%40project.iam.gserviceaccount.com",
+			wantErr: "impersonate_service_account cannot be used with disable_auth",
+		},
+		"connection properties": {
+			dsn: "bigquery://project/dataset?time_zone=Asia%2FTokyo&query_label=team%3Adata&dataset_project_id=data-project",
+			want: &Config{
+				ProjectID: "project",
+				DatasetID: "dataset",
+				ConnectionProperties: map[string]string{
+					"time_zone":          "Asia/Tokyo",
+					"query_label":        "team:data",
+					"dataset_project_id": "data-project",
+				},
+			},
+		},
+		"unsupported connection property": {
+			dsn:     "bigquery://project/dataset?session_id=abc",
+			wantErr: "unknown parameters in connection string: session_id",
+		},
+		"job creation mode": {
+			dsn:  "bigquery://project/dataset?job_creation_mode=optional",
+			want: &Config{ProjectID: "project", DatasetID: "dataset", JobCreationMode: bigquery.JobCreationModeOptional},
+		},
+		"translate placeholders": {
+			dsn:  "bigquery://project/dataset?translate_placeholders=true",
+			want: &Config{ProjectID: "project", DatasetID: "dataset", TranslatePlaceholders: true},
+		},
+		"temp functions": {
+			dsn:  "bigquery://project/dataset?temp_functions=masking,fiscal_calendar",
+			want: &Config{ProjectID: "project", DatasetID: "dataset", TempFunctions: []string{"masking", "fiscal_calendar"}},
+		},
+		"invalid job creation mode": {
+			dsn:     "bigquery://project/dataset?job_creation_mode=never",
+			wantErr: "invalid value for parameter 'job_creation_mode': never",
+		},
+		"invalid priority": {
+			dsn:     "bigquery://project/dataset?priority=urgent",
+			wantErr: "invalid value for parameter 'priority': urgent",
+		},
+		"invalid label": {
+			dsn:     "bigquery://project/dataset?labels=team",
+			wantErr: "invalid value for parameter 'labels': team",
+		},
+		"invalid maximum bytes billed": {
+			dsn:     "bigquery://project/dataset?maximum_bytes_billed=1GB",
+			wantErr: "invalid value for parameter 'maximum_bytes_billed': 1GB",
+		},
+		"invalid scheme": {
+			dsn:     "mysql://project/dataset",
+			wantErr: "invalid prefix, expected bigquery:// got: mysql://project/dataset",
+		},
+		"missing dataset": {
+			dsn:     "bigquery://project",
+			wantErr: "invalid connection string: bigquery://project",
+		},
+		"too many path segments": {
+			dsn:     "bigquery://project/a/b/c",
+			wantErr: "invalid connection string: bigquery://project/a/b/c",
+		},
+		"unknown parameters": {
+			dsn:     "bigquery://project/dataset?disable_auht=true&endpiont=x&scopes=a",
+			wantErr: "unknown parameters in connection string: disable_auht, endpiont",
+		},
+		"invalid bool": {
+			dsn:     "bigquery://project/dataset?disable_auth=yes",
+			wantErr: "invalid value for parameter 'disable_auth': yes",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseDSN(tt.dsn)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfigFormatDSN(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]*Config{
+		"minimal": {
+			ProjectID: "project",
+			DatasetID: "dataset",
+		},
+		"with location": {
+			ProjectID: "project",
+			Location:  "asia-northeast1",
+			DatasetID: "dataset",
+		},
+		"data project": {
+			ProjectID:     "billing-project",
+			DataProjectID: "example.com:data-project",
+			DatasetID:     "dataset",
+		},
+		"all fields": {
+			ProjectID:      "project",
+			Location:       "US",
+			DatasetID:      "dataset",
+			Scopes:         []string{"https://www.googleapis.com/auth/bigquery", "https://www.googleapis.com/auth/drive"},
+			Endpoint:       "http://localhost:9050",
+			DisableAuth:    true,
+			CredentialFile: "/path/to/key file.json",
+			CredentialJSON: []byte(`{"type":"service_account"}`),
+		},
+		"credential provider": {
+			ProjectID:          "project",
+			DatasetID:          "dataset",
+			CredentialProvider: "vault-prod",
+		},
+		"impersonation": {
+			ProjectID:                 "project",
+			DatasetID:                 "dataset",
+			CredentialJSON:            []byte(`{"type":"authorized_user"}`),
+			ImpersonateServiceAccount: "tenant@project.iam.gserviceaccount.com",
+			Delegates:                 []string{"a@project.iam.gserviceaccount.com"},
+			Lifetime:                  time.Hour,
+		},
+		"translate placeholders": {
+			ProjectID:             "project",
+			DatasetID:             "dataset",
+			TranslatePlaceholders: true,
+		},
+		"temp functions": {
+			ProjectID:     "project",
+			DatasetID:     "dataset",
+			TempFunctions: []string{"masking", "fiscal_calendar"},
+		},
+		"job defaults": {
+			ProjectID:               "project",
+			DatasetID:               "dataset",
+			MaxBytesBilled:          1 << 30,
+			Priority:                bigquery.InteractivePriority,
+			Labels:                  map[string]string{"team": "data", "env": "prod"},
+			DisableQueryCache:       true,
+			JobTimeout:              time.Minute,
+			UseLegacySQL:            true,
+			DisableFlattenedResults: true,
+			JobCreationMode:         bigquery.JobCreationModeOptional,
+			ConnectionProperties:    map[string]string{"time_zone": "Asia/Tokyo"},
+		},
+	}
+
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseDSN(config.FormatDSN())
+			require.NoError(t, err)
+			assert.Equal(t, config, got)
+		})
+	}
+}
+
+func TestConfigImpersonationConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config *Config
+		want   impersonate.CredentialsConfig
+	}{
+		"default scope": {
+			config: &Config{
+				ImpersonateServiceAccount: "tenant@project.iam.gserviceaccount.com",
+			},
+			want: impersonate.CredentialsConfig{
+				TargetPrincipal: "tenant@project.iam.gserviceaccount.com",
+				Scopes:          []string{bigquery.Scope},
+			},
+		},
+		"scopes, delegates and lifetime": {
+			config: &Config{
+				Scopes:                    []string{"https://www.googleapis.com/auth/drive"},
+				ImpersonateServiceAccount: "tenant@project.iam.gserviceaccount.com",
+				Delegates:                 []string{"a@project.iam.gserviceaccount.com"},
+				Lifetime:                  time.Hour,
+			},
+			want: impersonate.CredentialsConfig{
+				TargetPrincipal: "tenant@project.iam.gserviceaccount.com",
+				Scopes:          []string{"https://www.googleapis.com/auth/drive"},
+				Delegates:       []string{"a@project.iam.gserviceaccount.com"},
+				Lifetime:        time.Hour,
+			},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tt.config.impersonationConfig())
+		})
+	}
+}
+
+func TestConfigCredentialOptions(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	tests := map[string]struct {
+		config  *Config
+		wantLen int
+	}{
+		"none":                     {config: &Config{}, wantLen: 0},
+		"credential file":          {config: &Config{CredentialFile: "/path/key.json"}, wantLen: 1},
+		"credential file and JSON": {config: &Config{CredentialFile: "/path/key.json", CredentialJSON: []byte("{}")}, wantLen: 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := tt.config.credentialOptions(ctx)
+			require.NoError(t, err)
+			assert.Len(t, opts, tt.wantLen)
+		})
+	}
+}
+
+func TestConfigApplyJobDefaults(t *testing.T) {
+	t.Parallel()
+
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	config, err := ParseDSN("bigquery://project/dataset?maximum_bytes_billed=100&priority=batch&labels=team:data&use_query_cache=false&job_timeout=1m")
+	require.NoError(t, err)
+
+	connection := &bigQueryConnection{client: client, config: *config}
+	query, err := connection.query(context.Background(), "SELECT 1")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(100), query.MaxBytesBilled)
+	assert.Equal(t, bigquery.BatchPriority, query.Priority)
+	assert.Equal(t, map[string]string{"team": "data"}, query.Labels)
+	assert.True(t, query.DisableQueryCache)
+	assert.Equal(t, time.Minute, query.JobTimeout)
+
+	query.Labels["user"] = "alice"
+	assert.Equal(t, map[string]string{"team": "data"}, connection.config.Labels)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql/driver"
 	"fmt"
+	"strings"
 
 	"cloud.google.com/go/bigquery"
 )
@@ -11,36 +12,67 @@ import (
 type bigQueryConnection struct {
 	ctx     context.Context
 	client  *bigquery.Client
-	config  bigQueryConfig
+	config  Config
 	closed  bool
 	bad     bool
 	dataset *bigquery.Dataset
+	// closeClient is true when the client belongs to this connection only.
+	// Clients shared through a Connector are left open.
+	closeClient bool
+	userClients *userClientCache
+	// dryRuns caches the dry-run results of prepared statements by query.
+	dryRuns dryRunCache
+	// tempFunctions are added to the queries that call them.
+	tempFunctions []tempFunction
 }
 
 func (connection *bigQueryConnection) GetDataset() *bigquery.Dataset {
 	if connection.dataset != nil {
 		return connection.dataset
 	}
-	connection.dataset = connection.client.Dataset(connection.config.dataSet)
+	connection.dataset = connection.client.DatasetInProject(connection.config.dataProject(), connection.config.DatasetID)
 	return connection.dataset
 }
 
+// getDataset returns the dataset of the connection, or the one given by WithDefaultDataset.
+func (connection *bigQueryConnection) getDataset(ctx context.Context) *bigquery.Dataset {
+	projectID, datasetID := connection.config.defaultDatasetOf(ctx)
+	if projectID == connection.config.dataProject() && datasetID == connection.config.DatasetID {
+		return connection.GetDataset()
+	}
+	return connection.client.DatasetInProject(projectID, datasetID)
+}
+
 func (connection *bigQueryConnection) GetContext() context.Context {
 	return connection.ctx
 }
 
 func (connection *bigQueryConnection) Ping(ctx context.Context) error {
 
-	dataset := connection.GetDataset()
+	dataset := connection.getDataset(ctx)
 	if dataset == nil {
-		return fmt.Errorf("faild to ping using '%s' dataset", connection.config.dataSet)
+		return fmt.Errorf("faild to ping using '%s' dataset", connection.config.DatasetID)
 	}
 
-	_, err := dataset.Metadata(ctx)
+	metadata, err := dataset.Metadata(ctx)
 	if err != nil {
 		return err
 	}
 
+	return connection.checkLocation(dataset, metadata)
+}
+
+// checkLocation reports an error when the dataset lives in a different location
+// than the one given in the connection string, since every job would otherwise fail
+// with a "not found in location" error.
+func (connection *bigQueryConnection) checkLocation(dataset *bigquery.Dataset, metadata *bigquery.DatasetMetadata) error {
+	location := connection.config.Location
+	if location == "" || metadata == nil || metadata.Location == "" {
+		return nil
+	}
+	if !strings.EqualFold(location, metadata.Location) {
+		return fmt.Errorf("dataset '%s' is located in '%s', but the connection is configured for location '%s'", dataset.DatasetID, metadata.Location, location)
+	}
 	return nil
 }
 
@@ -50,17 +82,36 @@ func (connection *bigQueryConnection) QueryContext(ctx context.Context, query st
 }
 
 func (connection *bigQueryConnection) Query(query string, args []driver.Value) (driver.Rows, error) {
-	statement, err := connection.Prepare(query)
-	if err != nil {
-		return nil, nil
-	}
-
+	var statement = &bigQueryStatement{connection, query}
 	return statement.Query(args)
 }
 
 func (connection *bigQueryConnection) Prepare(query string) (driver.Stmt, error) {
-	var statement = &bigQueryStatement{connection, query}
+	return connection.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext validates queries without parameters with a dry-run job; see PreparedStatement.
+func (connection *bigQueryConnection) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	sql := query
+	if connection.config.TranslatePlaceholders {
+		translation, err := translatePlaceholders(sql)
+		if err != nil {
+			return nil, err
+		}
+		sql = translation.sql
+	}
+	placeholders, err := parsePlaceholders(sql)
+	if err != nil {
+		return nil, err
+	}
 
+	var statement = &bigQueryPreparedStatement{bigQueryStatement: &bigQueryStatement{connection, query}}
+	if placeholders.positional > 0 || len(placeholders.named) > 0 {
+		return statement, nil
+	}
+	if statement.dryRun, err = statement.dryRunQuery(ctx); err != nil {
+		return nil, err
+	}
 	return statement, nil
 }
 
@@ -72,6 +123,12 @@ func (connection *bigQueryConnection) Close() error {
 		return driver.ErrBadConn
 	}
 	connection.closed = true
+	if !connection.closeClient {
+		return nil
+	}
+	if connection.userClients != nil {
+		connection.userClients.close()
+	}
 	return connection.client.Close()
 }
 
@@ -81,8 +138,28 @@ func (connection *bigQueryConnection) Begin() (driver.Tx, error) {
 	return transaction, nil
 }
 
-func (connection *bigQueryConnection) query(query string) (*bigquery.Query, error) {
-	return connection.client.Query(query), nil
+// clientFor returns the client of the end user given through WithUserTokenSource,
+// or the client of the connection.
+func (connection *bigQueryConnection) clientFor(ctx context.Context) (*bigquery.Client, error) {
+	credentials := getUserCredentials(ctx)
+	if credentials == nil {
+		return connection.client, nil
+	}
+	if connection.userClients == nil {
+		connection.userClients = newUserClientCache(connection.config.UserClientCacheSize)
+	}
+	return connection.userClients.get(ctx, &connection.config, credentials)
+}
+
+func (connection *bigQueryConnection) query(ctx context.Context, query string) (*bigquery.Query, error) {
+	client, err := connection.clientFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q := client.Query(query)
+	q.Location = connection.config.Location
+	connection.config.applyJobDefaults(q)
+	return q, nil
 }
 
 func (connection *bigQueryConnection) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
@@ -95,7 +172,6 @@ func (connection *bigQueryConnection) Exec(query string, args []driver.Value) (d
 	return statement.Exec(args)
 }
 
-func (bigQueryConnection) CheckNamedValue(*driver.NamedValue) error {
-	// TODO: Revise in the future
-	return nil
+func (bigQueryConnection) CheckNamedValue(namedValue *driver.NamedValue) error {
+	return checkNamedValue(namedValue)
 }
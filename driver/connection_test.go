@@ -0,0 +1,122 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigQueryConnectionPingLocation(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/projects/project/datasets/dataset" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"datasetReference": map[string]string{"projectId": "project", "datasetId": "dataset"},
+			"location":         "US",
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	tests := map[string]struct {
+		location string
+		wantErr  string
+	}{
+		"no location": {
+			location: "",
+		},
+		"same location": {
+			location: "us",
+		},
+		"different location": {
+			location: "asia-northeast1",
+			wantErr:  "dataset 'dataset' is located in 'US', but the connection is configured for location 'asia-northeast1'",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := "/dataset"
+			if tt.location != "" {
+				path = "/" + tt.location + path
+			}
+			conn, err := bigQueryDriver{}.Open("bigquery://project" + path + "?disable_auth=true&endpoint=" + url.QueryEscape(server.URL+"/"))
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+
+			connection := conn.(*bigQueryConnection)
+			assert.Equal(t, tt.location, connection.client.Location)
+
+			err = connection.Ping(context.Background())
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBigQueryConnectionPingWithDefaultDataset(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		json.NewEncoder(w).Encode(map[string]any{"location": "US"})
+	})
+
+	conn, err := bigQueryDriver{}.Open(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	connection := conn.(*bigQueryConnection)
+	ctx := context.Background()
+	require.NoError(t, connection.Ping(ctx))
+	require.NoError(t, connection.Ping(WithDefaultDataset(ctx, "tenant_a")))
+	require.NoError(t, connection.Ping(WithDefaultDataset(ctx, "tenant-project.tenant_b")))
+
+	assert.Equal(t, []string{
+		"/projects/project/datasets/dataset",
+		"/projects/project/datasets/tenant_a",
+		"/projects/tenant-project/datasets/tenant_b",
+	}, paths)
+}
+
+func TestBigQueryConnectionJobCreationOptional(t *testing.T) {
+	t.Parallel()
+
+	var request map[string]any
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		// No jobReference is returned when BigQuery runs the query without a job.
+		json.NewEncoder(w).Encode(map[string]any{
+			"jobComplete": true,
+			"queryId":     "query",
+			"schema":      map[string]any{"fields": []map[string]string{{"name": "n", "type": "INTEGER"}}},
+			"totalRows":   "1",
+			"rows":        []map[string]any{{"f": []map[string]any{{"v": "1"}}}},
+		})
+	})
+
+	db, err := sql.Open("bigquery", dsn+"&job_creation_mode=optional")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	require.NoError(t, db.QueryRow("SELECT 1").Scan(&n))
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, "JOB_CREATION_OPTIONAL", request["jobCreationMode"])
+}
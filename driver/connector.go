@@ -0,0 +1,147 @@
+package driver
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// ConnectorOption configures a Connector created by NewConnector.
+type ConnectorOption func(*Connector)
+
+// WithConfig sets the configuration used by connections of the Connector.
+// The client related fields (scopes, endpoint and credentials) are ignored
+// because the Connector uses the given *bigquery.Client as is. JobCreationMode is
+// rejected by Connect for the same reason: create the client with
+// bigquery.WithDefaultJobCreationMode instead.
+func WithConfig(config *Config) ConnectorOption {
+	return func(connector *Connector) {
+		connector.config = *config
+	}
+}
+
+// WithDataset sets the default dataset of the Connector.
+func WithDataset(datasetID string) ConnectorOption {
+	return func(connector *Connector) {
+		connector.config.DatasetID = datasetID
+	}
+}
+
+// WithLocation sets the location used for every job started by the Connector.
+func WithLocation(location string) ConnectorOption {
+	return func(connector *Connector) {
+		connector.config.Location = location
+	}
+}
+
+// WithPlaceholderTranslation makes connections of the Connector accept the $n and :name
+// placeholders of other SQL dialects; see Config.TranslatePlaceholders.
+func WithPlaceholderTranslation() ConnectorOption {
+	return func(connector *Connector) {
+		connector.config.TranslatePlaceholders = true
+	}
+}
+
+// WithTempFunctions adds temporary functions to connections of the Connector, each given by its
+// CREATE TEMP FUNCTION statement; see RegisterTempFunctions. Invalid definitions are reported by Connect.
+func WithTempFunctions(definitions ...string) ConnectorOption {
+	return func(connector *Connector) {
+		connector.tempFunctions = append(connector.tempFunctions, definitions...)
+	}
+}
+
+var errJobCreationModeWithClient = errors.New("job_creation_mode cannot be used with a client given to NewConnector; create the client with bigquery.WithDefaultJobCreationMode instead")
+
+// Connector is a driver.Connector that shares one *bigquery.Client across all
+// connections opened by a *sql.DB.
+type Connector struct {
+	// client is nil when every connection creates its own client; see OpenConnector.
+	client      *bigquery.Client
+	config      Config
+	closeClient bool
+	userClients *userClientCache
+	// tempFunctions are the definitions of the temporary functions given by WithTempFunctions.
+	tempFunctions []string
+}
+
+var _ driver.Connector = (*Connector)(nil)
+
+// NewConnector returns a Connector that uses the given client for every connection.
+// The client is not closed by the Connector; the caller remains responsible for it.
+//
+//	db := sql.OpenDB(driver.NewConnector(client, driver.WithDataset("dataset")))
+func NewConnector(client *bigquery.Client, opts ...ConnectorOption) *Connector {
+	connector := &Connector{client: client}
+	for _, opt := range opts {
+		opt(connector)
+	}
+	if connector.config.ProjectID == "" {
+		connector.config.ProjectID = client.Project()
+	}
+	if connector.config.Location == "" {
+		connector.config.Location = client.Location
+	}
+	connector.userClients = newUserClientCache(connector.config.UserClientCacheSize)
+	return connector
+}
+
+func (connector *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	// The driver cannot tell whether a client given to NewConnector creates jobs, so it would
+	// stop cancelling them when their context is done.
+	if connector.config.JobCreationMode != "" && connector.client != nil && !connector.closeClient {
+		return nil, errJobCreationModeWithClient
+	}
+	tempFunctions, err := parseTempFunctions(connector.tempFunctions)
+	if err != nil {
+		return nil, err
+	}
+	tempFunctions, err = resolveTempFunctions(connector.config.TempFunctions, tempFunctions)
+	if err != nil {
+		return nil, err
+	}
+	connection := &bigQueryConnection{
+		ctx:           context.Background(),
+		client:        connector.client,
+		config:        connector.config,
+		userClients:   connector.userClients,
+		tempFunctions: tempFunctions,
+	}
+	if connector.client == nil {
+		client, err := newClient(connection.ctx, &connection.config)
+		if err != nil {
+			return nil, err
+		}
+		connection.client, connection.closeClient, connection.userClients = client, true, nil
+	}
+	return connection, nil
+}
+
+func (connector *Connector) Driver() driver.Driver {
+	return &bigQueryDriver{}
+}
+
+// Close closes the clients created for end users, and the underlying client when it was
+// created by the driver from a DSN. It is called by (*sql.DB).Close.
+func (connector *Connector) Close() error {
+	err := connector.userClients.close()
+	if !connector.closeClient || connector.client == nil {
+		return err
+	}
+	if closeErr := connector.client.Close(); closeErr != nil {
+		return closeErr
+	}
+	return err
+}
+
+type scannerConnector struct {
+}
+
+func (scannerConnector) Connect(context.Context) (driver.Conn, error) {
+	return &scannerConnection{}, nil
+}
+
+func (scannerConnector) Driver() driver.Driver {
+	return &bigQueryDriver{}
+}
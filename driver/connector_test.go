@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
+)
+
+func TestNewConnector(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	client.Location = "asia-northeast1"
+	t.Cleanup(func() { client.Close() })
+
+	connector := NewConnector(client, WithDataset("dataset"))
+	assert.Equal(t, Config{ProjectID: "project", Location: "asia-northeast1", DatasetID: "dataset"}, connector.config)
+
+	first, err := connector.Connect(ctx)
+	require.NoError(t, err)
+	second, err := connector.Connect(ctx)
+	require.NoError(t, err)
+
+	assert.Same(t, client, first.(*bigQueryConnection).client)
+	assert.Same(t, client, second.(*bigQueryConnection).client)
+	assert.False(t, first.(*bigQueryConnection).closeClient)
+	assert.NoError(t, first.Close())
+
+	db := sql.OpenDB(connector)
+	assert.NoError(t, db.Close())
+
+	connector = NewConnector(client, WithConfig(&Config{DatasetID: "dataset", JobCreationMode: bigquery.JobCreationModeOptional}))
+	_, err = connector.Connect(ctx)
+	assert.ErrorIs(t, err, errJobCreationModeWithClient)
+}
+
+func TestConnectorJobCreationModeFromContext(t *testing.T) {
+	t.Parallel()
+
+	var paths []string
+	endpoint := newFakeBigQueryEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		json.NewDecoder(r.Body).Decode(&request)
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if r.URL.Path != "/projects/project/queries" || request["jobCreationMode"] != "JOB_CREATION_OPTIONAL" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"jobComplete": true,
+			"queryId":     "query",
+			"schema":      map[string]any{"fields": []map[string]string{{"name": "n", "type": "INTEGER"}}},
+			"totalRows":   "1",
+			"rows":        []map[string]any{{"f": []map[string]any{{"v": "1"}}}},
+		})
+	})
+	serverURL, err := url.QueryUnescape(endpoint)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	client, err := bigquery.NewClient(ctx, "project", option.WithoutAuthentication(), option.WithEndpoint(serverURL),
+		bigquery.WithDefaultJobCreationMode(bigquery.JobCreationModeOptional))
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	db := sql.OpenDB(NewConnector(client, WithDataset("dataset")))
+	t.Cleanup(func() { db.Close() })
+
+	// A cancellable context would otherwise insert a job so that it can be cancelled.
+	ctx, cancel := context.WithCancel(WithJobCreationMode(ctx, bigquery.JobCreationModeOptional))
+	defer cancel()
+
+	var n int64
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, []string{"POST /projects/project/queries"}, paths)
+}
+
+func TestBigQueryDriverOpenConnector(t *testing.T) {
+	t.Parallel()
+
+	connector, err := bigQueryDriver{}.OpenConnector("bigquery://project/US/dataset?disable_auth=true")
+	require.NoError(t, err)
+
+	c := connector.(*Connector)
+	assert.True(t, c.closeClient)
+	assert.Equal(t, "US", c.client.Location)
+	assert.Equal(t, "dataset", c.config.DatasetID)
+
+	conn, err := c.Connect(context.Background())
+	require.NoError(t, err)
+	assert.False(t, conn.(*bigQueryConnection).closeClient)
+	assert.NoError(t, c.Close())
+
+	connector, err = bigQueryDriver{}.OpenConnector("bigquery://project/dataset?disable_auth=true&job_creation_mode=optional")
+	require.NoError(t, err)
+	_, err = connector.Connect(context.Background())
+	assert.NoError(t, err)
+	assert.NoError(t, connector.(*Connector).Close())
+
+	_, err = bigQueryDriver{}.OpenConnector("bigquery://project/dataset?unknown=1")
+	assert.EqualError(t, err, "unknown parameters in connection string: unknown")
+}
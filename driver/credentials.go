@@ -0,0 +1,57 @@
+package driver
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/api/option"
+)
+
+// CredentialProvider resolves the credentials of a client when a connection is opened.
+type CredentialProvider func(ctx context.Context) (option.ClientOption, error)
+
+var (
+	credentialProvidersMutex sync.RWMutex
+	credentialProviders      = make(map[string]CredentialProvider)
+)
+
+// RegisterCredentialProvider makes a credential provider available by the provided name.
+// A DSN refers to it with the "credentials" parameter, for example "bigquery://project/dataset?credentials=vault-prod".
+// If RegisterCredentialProvider is called twice with the same name or if provider is nil, it panics.
+func RegisterCredentialProvider(name string, provider CredentialProvider) {
+	credentialProvidersMutex.Lock()
+	defer credentialProvidersMutex.Unlock()
+
+	if provider == nil {
+		panic("bigquery: RegisterCredentialProvider provider is nil")
+	}
+	if _, dup := credentialProviders[name]; dup {
+		panic("bigquery: RegisterCredentialProvider called twice for provider " + name)
+	}
+	credentialProviders[name] = provider
+}
+
+// DeregisterCredentialProvider removes the credential provider registered with the given name.
+func DeregisterCredentialProvider(name string) {
+	credentialProvidersMutex.Lock()
+	defer credentialProvidersMutex.Unlock()
+
+	delete(credentialProviders, name)
+}
+
+func resolveCredentialProvider(ctx context.Context, name string) (option.ClientOption, error) {
+	credentialProvidersMutex.RLock()
+	provider, ok := credentialProviders[name]
+	credentialProvidersMutex.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown credential provider '%s'", name)
+	}
+
+	opt, err := provider(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials from provider '%s': %w", name, err)
+	}
+	return opt, nil
+}
@@ -0,0 +1,73 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+func TestRegisterCredentialProvider(t *testing.T) {
+	var authorization string
+	endpoint := newFakeBigQueryEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		writeQueryResponse(w, "1")
+	})
+	dsn := "bigquery://project/dataset?endpoint=" + endpoint
+
+	var calls int
+	RegisterCredentialProvider("test-provider", func(ctx context.Context) (option.ClientOption, error) {
+		calls++
+		return option.WithTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: fmt.Sprintf("secret-%d", calls)})), nil
+	})
+	RegisterCredentialProvider("failing-provider", func(ctx context.Context) (option.ClientOption, error) {
+		return nil, errors.New("vault is sealed")
+	})
+	t.Cleanup(func() {
+		DeregisterCredentialProvider("test-provider")
+		DeregisterCredentialProvider("failing-provider")
+	})
+
+	assert.Panics(t, func() {
+		RegisterCredentialProvider("test-provider", func(ctx context.Context) (option.ClientOption, error) { return nil, nil })
+	})
+	assert.Panics(t, func() { RegisterCredentialProvider("nil-provider", nil) })
+
+	db, err := sql.Open("bigquery", dsn+"&credentials=test-provider")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	// The provider is consulted for every connection, so that a rotated secret is picked up.
+	ctx := context.Background()
+	first, err := db.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { first.Close() })
+	var n int64
+	require.NoError(t, first.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	assert.Equal(t, "Bearer secret-1", authorization)
+
+	second, err := db.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { second.Close() })
+	require.NoError(t, second.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	assert.Equal(t, "Bearer secret-2", authorization)
+	assert.Equal(t, 2, calls)
+
+	for provider, wantErr := range map[string]string{
+		"failing-provider": "failed to get credentials from provider 'failing-provider': vault is sealed",
+		"unknown-provider": "unknown credential provider 'unknown-provider'",
+	} {
+		db, err := sql.Open("bigquery", dsn+"&credentials="+provider)
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		_, err = db.Conn(ctx)
+		assert.EqualError(t, err, wantErr)
+	}
+}
@@ -0,0 +1,42 @@
+package driver
+
+import (
+	"context"
+	"strings"
+)
+
+var defaultDatasetCtxKey = struct{ value string }{"defaultDatasetCtxKey"}
+
+type defaultDataset struct {
+	projectID string
+	datasetID string
+}
+
+// WithDefaultDataset returns a context whose queries use the given dataset as the default dataset
+// instead of the one in the DSN. The dataset is written like in the DSN, either "dataset"
+// or "project.dataset" to also change the data project.
+//
+//	ctx = driver.WithDefaultDataset(ctx, "tenant_a")
+//	db.WithContext(ctx).Find(&records)
+func WithDefaultDataset(ctx context.Context, dataset string) context.Context {
+	value := defaultDataset{datasetID: dataset}
+	if index := strings.LastIndex(dataset, "."); index != -1 {
+		value.projectID = dataset[:index]
+		value.datasetID = dataset[index+1:]
+	}
+	return context.WithValue(ctx, defaultDatasetCtxKey, value)
+}
+
+// defaultDatasetOf returns the project and dataset that queries with ctx resolve against.
+func (config *Config) defaultDatasetOf(ctx context.Context) (string, string) {
+	if ctx != nil {
+		if value, ok := ctx.Value(defaultDatasetCtxKey).(defaultDataset); ok {
+			projectID := value.projectID
+			if projectID == "" {
+				projectID = config.dataProject()
+			}
+			return projectID, value.datasetID
+		}
+	}
+	return config.dataProject(), config.DatasetID
+}
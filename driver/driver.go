@@ -3,119 +3,91 @@ package driver
 import (
 	"context"
 	"database/sql/driver"
-	"encoding/base64"
-	"fmt"
-	"net/url"
-	"strings"
 
 	"cloud.google.com/go/bigquery"
-	"google.golang.org/api/option"
 )
 
 type bigQueryDriver struct {
 }
 
-type bigQueryConfig struct {
-	projectID      string
-	location       string
-	dataSet        string
-	scopes         []string
-	endpoint       string
-	disableAuth    bool
-	credentialFile string
-	credentialJSON []byte
-}
+var _ driver.DriverContext = (*bigQueryDriver)(nil)
 
 func (b bigQueryDriver) Open(uri string) (driver.Conn, error) {
 	if uri == "scanner" {
 		return &scannerConnection{}, nil
 	}
 
-	config, err := configFromUri(uri)
+	config, err := ParseDSN(uri)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx := context.Background()
-
-	opts := []option.ClientOption{option.WithScopes(config.scopes...)}
-	if config.endpoint != "" {
-		opts = append(opts, option.WithEndpoint(config.endpoint))
-	}
-	if config.disableAuth {
-		opts = append(opts, option.WithoutAuthentication())
-	}
-	if config.credentialFile != "" {
-		opts = append(opts, option.WithCredentialsFile(config.credentialFile))
-	}
-	if len(config.credentialJSON) != 0 {
-		opts = append(opts, option.WithCredentialsJSON([]byte(config.credentialJSON)))
+	tempFunctions, err := resolveTempFunctions(config.TempFunctions, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := bigquery.NewClient(ctx, config.projectID, opts...)
+	ctx := context.Background()
+
+	client, err := newClient(ctx, config)
 	if err != nil {
 		return nil, err
 	}
 
 	return &bigQueryConnection{
-		ctx:    ctx,
-		client: client,
-		config: *config,
+		ctx:           ctx,
+		client:        client,
+		config:        *config,
+		closeClient:   true,
+		tempFunctions: tempFunctions,
 	}, nil
 }
 
-func configFromUri(uri string) (*bigQueryConfig, error) {
-	u, err := url.Parse(uri)
-	if err != nil {
-		return nil, invalidConnectionStringError(uri)
-	}
-
-	if u.Scheme != "bigquery" {
-		return nil, fmt.Errorf("invalid prefix, expected bigquery:// got: %s", uri)
-	}
-
-	if u.Path == "" {
-		return nil, invalidConnectionStringError(uri)
+// OpenConnector creates a single client for the DSN that is shared by every
+// connection in the pool and closed together with the *sql.DB.
+// DSNs naming a credential provider create a client for every connection instead,
+// so that the provider is consulted again and rotated credentials are picked up.
+func (b bigQueryDriver) OpenConnector(uri string) (driver.Connector, error) {
+	if uri == "scanner" {
+		return &scannerConnector{}, nil
 	}
 
-	fields := strings.Split(strings.TrimPrefix(u.Path, "/"), "/")
-	if len(fields) > 2 {
-		return nil, invalidConnectionStringError(uri)
+	config, err := ParseDSN(uri)
+	if err != nil {
+		return nil, err
 	}
-
-	config := &bigQueryConfig{
-		projectID:      u.Hostname(),
-		dataSet:        fields[len(fields)-1],
-		scopes:         getScopes(u.Query()),
-		endpoint:       u.Query().Get("endpoint"),
-		disableAuth:    u.Query().Get("disable_auth") == "true",
-		credentialFile: u.Query().Get("credential_file"),
+	if _, err := resolveTempFunctions(config.TempFunctions, nil); err != nil {
+		return nil, err
 	}
 
-	if u.Query().Get("credential_json") != "" {
-		credentialsJSON, err := base64.StdEncoding.DecodeString(u.Query().Get("credential_json"))
-		if err != nil {
-			return nil, err
-		} else {
-			config.credentialJSON = credentialsJSON
-		}
+	if config.CredentialProvider != "" {
+		return &Connector{
+			config:      *config,
+			closeClient: true,
+			userClients: newUserClientCache(config.UserClientCacheSize),
+		}, nil
 	}
 
-	if len(fields) == 2 {
-		config.location = fields[0]
+	client, err := newClient(context.Background(), config)
+	if err != nil {
+		return nil, err
 	}
 
-	return config, nil
+	connector := NewConnector(client, WithConfig(config))
+	connector.closeClient = true
+	return connector, nil
 }
 
-func getScopes(query url.Values) []string {
-	q := strings.Trim(query.Get("scopes"), ",")
-	if q == "" {
-		return []string{}
+func newClient(ctx context.Context, config *Config) (*bigquery.Client, error) {
+	opts, err := config.clientOptions(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return strings.Split(q, ",")
-}
 
-func invalidConnectionStringError(uri string) error {
-	return fmt.Errorf("invalid connection string: %s", uri)
+	client, err := bigquery.NewClient(ctx, config.ProjectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Location = config.Location
+	return client, nil
 }
@@ -0,0 +1,83 @@
+package driver
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeBigQuery starts a server that serves the BigQuery REST API with handler
+// and returns a DSN that points the driver to it.
+func newFakeBigQuery(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+
+	return "bigquery://project/dataset?disable_auth=true&endpoint=" + newFakeBigQueryEndpoint(t, handler)
+}
+
+// newFakeBigQueryEndpoint starts a server that serves the BigQuery REST API with handler
+// and returns its endpoint escaped for the "endpoint" DSN parameter.
+func newFakeBigQueryEndpoint(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return url.QueryEscape(server.URL + "/")
+}
+
+// writeQueryResponse writes a completed jobs.query response with a single INTEGER column "n".
+func writeQueryResponse(w http.ResponseWriter, values ...string) {
+	rows := make([]map[string]any, 0, len(values))
+	for _, value := range values {
+		rows = append(rows, map[string]any{"f": []map[string]any{{"v": value}}})
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"jobComplete":  true,
+		"jobReference": map[string]string{"projectId": "project", "jobId": "job", "location": "US"},
+		"schema":       map[string]any{"fields": []map[string]string{{"name": "n", "type": "INTEGER"}}},
+		"totalRows":    strconv.Itoa(len(values)),
+		"rows":         rows,
+	})
+}
+
+// writeJobStatistics writes a completed query job "job" with the given query statistics.
+func writeJobStatistics(w http.ResponseWriter, statistics map[string]any) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"jobReference":  map[string]string{"projectId": "project", "jobId": "job", "location": "US"},
+		"configuration": map[string]any{"query": map[string]any{"query": "SELECT 1"}},
+		"statistics":    map[string]any{"creationTime": "1000", "query": statistics},
+		"status":        map[string]any{"state": "DONE"},
+	})
+}
+
+// sendQueryParameter runs a query with value as its only parameter and returns the
+// parameterType and parameterValue the driver sent for it.
+func sendQueryParameter(t *testing.T, value any) (map[string]any, map[string]any) {
+	t.Helper()
+
+	var request struct {
+		QueryParameters []struct {
+			ParameterType  map[string]any `json:"parameterType"`
+			ParameterValue map[string]any `json:"parameterValue"`
+		} `json:"queryParameters"`
+	}
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		writeQueryResponse(w, "1")
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	require.NoError(t, db.QueryRow("SELECT 1 WHERE ? IS NOT NULL", value).Scan(&n))
+	require.Len(t, request.QueryParameters, 1)
+	return request.QueryParameters[0].ParameterType, request.QueryParameters[0].ParameterValue
+}
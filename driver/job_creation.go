@@ -0,0 +1,47 @@
+package driver
+
+import (
+	"context"
+
+	"cloud.google.com/go/bigquery"
+)
+
+var jobCreationModeCtxKey = struct{ value string }{"jobCreationModeCtxKey"}
+
+// requiredJobIDPrefix starts the IDs of the jobs created by requireJobCreation.
+const requiredJobIDPrefix = "go_bigquery"
+
+// WithJobCreationMode returns a context whose queries use the given job creation mode instead of
+// the job_creation_mode of the connection.
+//
+// bigquery.JobCreationModeRequired makes every query create a job, for example to look the job up later.
+// bigquery.JobCreationModeOptional lets BigQuery run short queries without a job. It only takes effect
+// when the client allows it, that is when the DSN sets job_creation_mode=optional or the client given
+// to NewConnector was created with bigquery.WithDefaultJobCreationMode. Scripts and DML statements
+// create a job in either mode.
+//
+//	ctx = driver.WithJobCreationMode(ctx, bigquery.JobCreationModeOptional)
+//	db.WithContext(ctx).Find(&records)
+func WithJobCreationMode(ctx context.Context, mode bigquery.JobCreationMode) context.Context {
+	return context.WithValue(ctx, jobCreationModeCtxKey, mode)
+}
+
+// jobCreationModeOf returns the job creation mode of queries run with ctx.
+func (config *Config) jobCreationModeOf(ctx context.Context) bigquery.JobCreationMode {
+	if ctx != nil {
+		if mode, ok := ctx.Value(jobCreationModeCtxKey).(bigquery.JobCreationMode); ok && mode != "" && mode != bigquery.JobCreationModeUnspecified {
+			return mode
+		}
+	}
+	return config.JobCreationMode
+}
+
+// requireJobCreation makes the client submit the query with jobs.insert, which always creates
+// a job: BigQuery only runs queries without a job when the caller leaves the job ID to it.
+// The job is given a random ID made of requiredJobIDPrefix and the suffix added by the client.
+func requireJobCreation(query *bigquery.Query) {
+	if query.JobID == "" {
+		query.JobID = requiredJobIDPrefix
+		query.AddJobIDSuffix = true
+	}
+}
@@ -0,0 +1,243 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenSpace tokenKind = iota
+	tokenComment
+	// tokenString is a string or bytes literal, including raw and triple-quoted ones.
+	tokenString
+	// tokenQuotedIdentifier is an identifier quoted with backticks.
+	tokenQuotedIdentifier
+	// tokenWord is a keyword, an unquoted identifier, a number or a system variable such as @@project_id.
+	tokenWord
+	// tokenPositional is a positional query parameter: ?
+	tokenPositional
+	// tokenNamed is a named query parameter such as @name.
+	tokenNamed
+	// tokenSymbol is an operator or a punctuation character.
+	tokenSymbol
+)
+
+type sqlToken struct {
+	kind tokenKind
+	text string
+}
+
+// tokenizeSQL splits GoogleSQL text into tokens, so that question marks and at signs in
+// literals, comments and quoted identifiers are not taken for query parameters.
+// It does not validate the SQL: an unterminated literal or comment runs to the end of the text.
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(sql); {
+		kind, end := scanToken(sql, i)
+		tokens = append(tokens, sqlToken{kind, sql[i:end]})
+		i = end
+	}
+	return tokens
+}
+
+// scanToken returns the kind and the end of the token starting at start.
+func scanToken(sql string, start int) (tokenKind, int) {
+	c := sql[start]
+	switch {
+	case isSpace(c):
+		end := start
+		for end < len(sql) && isSpace(sql[end]) {
+			end++
+		}
+		return tokenSpace, end
+	case c == '#', strings.HasPrefix(sql[start:], "--"):
+		end := strings.IndexByte(sql[start:], '\n')
+		if end == -1 {
+			return tokenComment, len(sql)
+		}
+		return tokenComment, start + end + 1
+	case strings.HasPrefix(sql[start:], "/*"):
+		end := strings.Index(sql[start+2:], "*/")
+		if end == -1 {
+			return tokenComment, len(sql)
+		}
+		return tokenComment, start + 2 + end + 2
+	case c == '\'', c == '"':
+		return tokenString, scanString(sql, start)
+	case c == '`':
+		return tokenQuotedIdentifier, scanQuoted(sql, start, "`")
+	case c == '?':
+		return tokenPositional, start + 1
+	case c == '@':
+		if strings.HasPrefix(sql[start:], "@@") && start+2 < len(sql) && isWordStart(sql[start+2]) {
+			return tokenWord, scanWord(sql, start+2)
+		}
+		if start+1 < len(sql) && isWordStart(sql[start+1]) {
+			return tokenNamed, scanWord(sql, start+1)
+		}
+		return tokenSymbol, start + 1
+	case isWordByte(c):
+		end := scanWord(sql, start)
+		// String and bytes literals may have an r, b, rb or br prefix.
+		if end < len(sql) && (sql[end] == '\'' || sql[end] == '"') && isLiteralPrefix(sql[start:end]) {
+			return tokenString, scanString(sql, end)
+		}
+		return tokenWord, end
+	}
+	return tokenSymbol, start + 1
+}
+
+// scanString returns the end of the string literal whose opening quote is at start.
+func scanString(sql string, start int) int {
+	quote := sql[start : start+1]
+	if strings.HasPrefix(sql[start:], strings.Repeat(quote, 3)) {
+		quote = strings.Repeat(quote, 3)
+	}
+	return scanQuoted(sql, start, quote)
+}
+
+// scanQuoted returns the end of the text quoted with quote starting at start.
+// A backslash escapes the next character, also in raw literals where it is kept as it is.
+func scanQuoted(sql string, start int, quote string) int {
+	for i := start + len(quote); i < len(sql); i++ {
+		switch {
+		case sql[i] == '\\':
+			i++
+		case strings.HasPrefix(sql[i:], quote):
+			return i + len(quote)
+		}
+	}
+	return len(sql)
+}
+
+func scanWord(sql string, start int) int {
+	end := start
+	for end < len(sql) && isWordByte(sql[end]) {
+		end++
+	}
+	return end
+}
+
+func isLiteralPrefix(word string) bool {
+	switch strings.ToLower(word) {
+	case "r", "b", "rb", "br":
+		return true
+	}
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isWordByte(c byte) bool {
+	return isWordStart(c) || '0' <= c && c <= '9' || c >= 0x80
+}
+
+// significantTokens returns the tokens other than spaces and comments.
+func significantTokens(tokens []sqlToken) []sqlToken {
+	var significant []sqlToken
+	for _, token := range tokens {
+		if token.kind != tokenSpace && token.kind != tokenComment {
+			significant = append(significant, token)
+		}
+	}
+	return significant
+}
+
+// sqlPlaceholders are the query parameters used by SQL text.
+type sqlPlaceholders struct {
+	// positional is the number of ? placeholders.
+	positional int
+	// named are the names of the @name placeholders, without duplicates, in order of appearance.
+	named []string
+}
+
+var errMixedPlaceholders = errors.New("query mixes positional (?) and named (@name) parameters, which BigQuery does not support")
+
+// parsePlaceholders collects the query parameters used by sql.
+func parsePlaceholders(sql string) (sqlPlaceholders, error) {
+	var placeholders sqlPlaceholders
+	seen := make(map[string]bool)
+	for _, token := range tokenizeSQL(sql) {
+		switch token.kind {
+		case tokenPositional:
+			placeholders.positional++
+		case tokenNamed:
+			// Parameter names are case-insensitive.
+			name := token.text[1:]
+			if !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				placeholders.named = append(placeholders.named, name)
+			}
+		}
+	}
+	if placeholders.positional > 0 && len(placeholders.named) > 0 {
+		return sqlPlaceholders{}, errMixedPlaceholders
+	}
+	return placeholders, nil
+}
+
+// check reports arguments that do not match the placeholders before a job is submitted.
+func (placeholders sqlPlaceholders) check(args []driver.Value) error {
+	if len(placeholders.named) == 0 {
+		if len(args) != placeholders.positional {
+			return fmt.Errorf("query has %d positional parameters, but %d arguments were given", placeholders.positional, len(args))
+		}
+		for _, arg := range args {
+			if namedValue, ok := arg.(driver.NamedValue); ok && namedValue.Name != "" {
+				return fmt.Errorf("query uses positional parameters, but the argument @%s is named", namedValue.Name)
+			}
+		}
+		return nil
+	}
+
+	names := make(map[string]bool, len(args))
+	for _, arg := range args {
+		namedValue, ok := arg.(driver.NamedValue)
+		if !ok || namedValue.Name == "" {
+			return fmt.Errorf("query uses named parameters, but argument %s has no name", parameterName("", arg))
+		}
+		names[strings.ToLower(namedValue.Name)] = true
+	}
+	for _, name := range placeholders.named {
+		if !names[strings.ToLower(name)] {
+			return fmt.Errorf("no argument was given for the parameter @%s", name)
+		}
+	}
+	return nil
+}
+
+// isSingleQueryStatement reports whether sql is a single SELECT statement, as opposed to
+// a DML or DDL statement or a script. It errs on the side of false.
+func isSingleQueryStatement(sql string) bool {
+	tokens := significantTokens(tokenizeSQL(sql))
+	for len(tokens) > 0 && tokens[0].text == "(" {
+		tokens = tokens[1:]
+	}
+	for len(tokens) > 0 && tokens[len(tokens)-1].text == ";" {
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 || tokens[0].kind != tokenWord {
+		return false
+	}
+
+	switch strings.ToUpper(tokens[0].text) {
+	case "SELECT", "WITH":
+		for _, token := range tokens {
+			if token.kind == tokenSymbol && token.text == ";" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sql  string
+		want []sqlToken
+	}{
+		"placeholders": {
+			sql: "a = ? AND b = @name",
+			want: []sqlToken{
+				{tokenWord, "a"}, {tokenSpace, " "}, {tokenSymbol, "="}, {tokenSpace, " "}, {tokenPositional, "?"},
+				{tokenSpace, " "}, {tokenWord, "AND"}, {tokenSpace, " "}, {tokenWord, "b"}, {tokenSpace, " "},
+				{tokenSymbol, "="}, {tokenSpace, " "}, {tokenNamed, "@name"},
+			},
+		},
+		"strings": {
+			sql:  `'a?' "b\"?" '''c'?''' """d"?"""`,
+			want: []sqlToken{{tokenString, `'a?'`}, {tokenSpace, " "}, {tokenString, `"b\"?"`}, {tokenSpace, " "}, {tokenString, `'''c'?'''`}, {tokenSpace, " "}, {tokenString, `"""d"?"""`}},
+		},
+		"raw and bytes literals": {
+			sql:  `r'\d?' B"?" rb'''?''' bR"x"`,
+			want: []sqlToken{{tokenString, `r'\d?'`}, {tokenSpace, " "}, {tokenString, `B"?"`}, {tokenSpace, " "}, {tokenString, `rb'''?'''`}, {tokenSpace, " "}, {tokenString, `bR"x"`}},
+		},
+		"comments": {
+			sql:  "-- ?\n# @a\n/* ? @b */x",
+			want: []sqlToken{{tokenComment, "-- ?\n"}, {tokenComment, "# @a\n"}, {tokenComment, "/* ? @b */"}, {tokenWord, "x"}},
+		},
+		"quoted identifier": {
+			sql:  "`my-project.a?b`.c",
+			want: []sqlToken{{tokenQuotedIdentifier, "`my-project.a?b`"}, {tokenSymbol, "."}, {tokenWord, "c"}},
+		},
+		"system variable": {
+			sql:  "@@project_id",
+			want: []sqlToken{{tokenWord, "@@project_id"}},
+		},
+		"unterminated string": {
+			sql:  "'abc ?",
+			want: []sqlToken{{tokenString, "'abc ?"}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tokenizeSQL(tt.sql))
+		})
+	}
+}
+
+func TestParsePlaceholders(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sql     string
+		want    sqlPlaceholders
+		wantErr string
+	}{
+		"positional": {
+			sql:  "SELECT * FROM t WHERE a = ? AND b IN UNNEST(?)",
+			want: sqlPlaceholders{positional: 2},
+		},
+		"named": {
+			sql:  "SELECT * FROM t WHERE a = @a AND b = @B OR c = @b",
+			want: sqlPlaceholders{named: []string{"a", "B"}},
+		},
+		"ignored": {
+			sql: "SELECT '?', \"@a\", `?`, @@row_count -- ?\n/* @b */",
+		},
+		"mixed": {
+			sql:     "SELECT ? + @a",
+			wantErr: errMixedPlaceholders.Error(),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parsePlaceholders(tt.sql)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSQLPlaceholdersCheck(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		placeholders sqlPlaceholders
+		args         []driver.Value
+		wantErr      string
+	}{
+		"positional": {
+			placeholders: sqlPlaceholders{positional: 1},
+			args:         []driver.Value{driver.NamedValue{Ordinal: 1, Value: 1}},
+		},
+		"too few arguments": {
+			placeholders: sqlPlaceholders{positional: 2},
+			args:         []driver.Value{driver.NamedValue{Ordinal: 1, Value: 1}},
+			wantErr:      "query has 2 positional parameters, but 1 arguments were given",
+		},
+		"named argument for positional parameter": {
+			placeholders: sqlPlaceholders{positional: 1},
+			args:         []driver.Value{driver.NamedValue{Name: "a", Ordinal: 1, Value: 1}},
+			wantErr:      "query uses positional parameters, but the argument @a is named",
+		},
+		"named": {
+			placeholders: sqlPlaceholders{named: []string{"a"}},
+			args:         []driver.Value{driver.NamedValue{Name: "A", Ordinal: 1, Value: 1}, driver.NamedValue{Name: "b", Ordinal: 2, Value: 2}},
+		},
+		"unnamed argument for named parameter": {
+			placeholders: sqlPlaceholders{named: []string{"a"}},
+			args:         []driver.Value{driver.NamedValue{Ordinal: 1, Value: 1}},
+			wantErr:      "query uses named parameters, but argument 1 has no name",
+		},
+		"missing named argument": {
+			placeholders: sqlPlaceholders{named: []string{"a", "b"}},
+			args:         []driver.Value{driver.NamedValue{Name: "a", Ordinal: 1, Value: 1}},
+			wantErr:      "no argument was given for the parameter @b",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tt.placeholders.check(tt.args)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBigQueryStatementPlaceholdersCheckedBeforeJob(t *testing.T) {
+	t.Parallel()
+
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		http.Error(w, "unexpected", http.StatusInternalServerError)
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Query("SELECT * FROM t WHERE a = ? AND b = ?", 1)
+	assert.EqualError(t, err, "query has 2 positional parameters, but 1 arguments were given")
+
+	_, err = db.Query("SELECT * FROM t WHERE a = ? AND b = @b", 1, sql.Named("b", 2))
+	assert.ErrorIs(t, err, errMixedPlaceholders)
+
+	stmt, err := db.Prepare("SELECT * FROM t WHERE a = ? AND note = '?'")
+	require.NoError(t, err)
+	t.Cleanup(func() { stmt.Close() })
+	_, err = stmt.Query()
+	assert.EqualError(t, err, "sql: expected 1 arguments, got 0")
+}
@@ -0,0 +1,509 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+var (
+	typeOfValuer        = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	typeOfGoTime        = reflect.TypeOf(time.Time{})
+	typeOfRat           = reflect.TypeOf(big.Rat{})
+	typeOfDate          = reflect.TypeOf(civil.Date{})
+	typeOfTime          = reflect.TypeOf(civil.Time{})
+	typeOfDateTime      = reflect.TypeOf(civil.DateTime{})
+	typeOfIntervalValue = reflect.TypeOf(bigquery.IntervalValue{})
+	typeOfDuration      = reflect.TypeOf(time.Duration(0))
+	typeOfNumeric       = reflect.TypeOf(Numeric(""))
+	typeOfBigNumeric    = reflect.TypeOf(BigNumeric(""))
+	typeOfJSON          = reflect.TypeOf(JSON(""))
+	typeOfGeography     = reflect.TypeOf(Geography(""))
+)
+
+// valuerValueType returns the Go type of the value held by a driver.Valuer, such as the
+// field V of sql.Null[T] or the field String of sql.NullString.
+func valuerValueType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return t
+	}
+	if field, ok := t.FieldByName("V"); ok {
+		return field.Type
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Name != "Valid" {
+			return field.Type
+		}
+	}
+	return t
+}
+
+// nullParameterValueOf returns a NULL query parameter value with the BigQuery type of t.
+func nullParameterValueOf(t reflect.Type) (interface{}, error) {
+	switch t {
+	case typeOfGoTime:
+		return bigquery.NullTimestamp{}, nil
+	case typeOfDate:
+		return bigquery.NullDate{}, nil
+	case typeOfTime:
+		return bigquery.NullTime{}, nil
+	case typeOfDateTime:
+		return bigquery.NullDateTime{}, nil
+	case typeOfRat:
+		return typedNullParameterValue(bigquery.NumericFieldType), nil
+	case typeOfIntervalValue, typeOfDuration:
+		return typedNullParameterValue(bigquery.IntervalFieldType), nil
+	case typeOfDateRange:
+		return &bigquery.QueryParameterValue{Type: rangeParameterType(bigquery.DateFieldType), Value: bigquery.NullString{}}, nil
+	case typeOfDateTimeRange:
+		return &bigquery.QueryParameterValue{Type: rangeParameterType(bigquery.DateTimeFieldType), Value: bigquery.NullString{}}, nil
+	case typeOfTimestampRange:
+		return &bigquery.QueryParameterValue{Type: rangeParameterType(bigquery.TimestampFieldType), Value: bigquery.NullString{}}, nil
+	case typeOfNumeric:
+		return typedNullParameterValue(bigquery.NumericFieldType), nil
+	case typeOfBigNumeric:
+		return typedNullParameterValue(bigquery.BigNumericFieldType), nil
+	case typeOfJSON:
+		return bigquery.NullJSON{}, nil
+	case typeOfGeography:
+		return bigquery.NullGeography{}, nil
+	}
+
+	if isStructParameterType(t) {
+		structType, err := structParameterType(t, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &bigquery.QueryParameterValue{Type: *structType, Value: bigquery.NullString{}}, nil
+	}
+
+	if t.Implements(typeOfValuer) || reflect.PointerTo(t).Implements(typeOfValuer) {
+		if valueType := valuerValueType(t); valueType != t {
+			return nullParameterValueOf(valueType)
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return bigquery.NullInt64{}, nil
+	case reflect.Float32, reflect.Float64:
+		return bigquery.NullFloat64{}, nil
+	case reflect.Bool:
+		return bigquery.NullBool{}, nil
+	case reflect.String:
+		return bigquery.NullString{}, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return typedNullParameterValue(bigquery.BytesFieldType), nil
+		}
+	}
+	return nil, fmt.Errorf("cannot determine the BigQuery type of a NULL %s parameter", t)
+}
+
+// typedNullParameterValue returns a NULL value of a type that has no Null type in the client library.
+// An invalid NullString is sent without a value, so the declared type is the only one sent.
+func typedNullParameterValue(fieldType bigquery.FieldType) *bigquery.QueryParameterValue {
+	return &bigquery.QueryParameterValue{
+		Type:  bigquery.StandardSQLDataType{TypeKind: string(fieldType)},
+		Value: bigquery.NullString{},
+	}
+}
+
+// Numeric is a decimal string sent as a NUMERIC query parameter.
+type Numeric string
+
+// BigNumeric is a decimal string sent as a BIGNUMERIC query parameter.
+type BigNumeric string
+
+// JSON is a JSON document sent as a JSON query parameter.
+type JSON string
+
+// Geography is a WKT or GeoJSON string sent as a GEOGRAPHY query parameter.
+type Geography string
+
+// convertParameterValue converts a Go value to a value whose BigQuery type is explicit,
+// so that unsupported values are rejected before a job is submitted.
+// Values the client library already types correctly are returned as they are.
+func convertParameterValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, bigQueryReroutedColumn, *bigquery.QueryParameterValue,
+		bigquery.NullString, bigquery.NullInt64, bigquery.NullFloat64, bigquery.NullBool,
+		bigquery.NullTimestamp, bigquery.NullDate, bigquery.NullTime, bigquery.NullDateTime,
+		bigquery.NullGeography, bigquery.NullJSON, time.Time:
+		return value, nil
+	case Numeric:
+		return decimalParameterValue(bigquery.NumericFieldType, string(v))
+	case BigNumeric:
+		return decimalParameterValue(bigquery.BigNumericFieldType, string(v))
+	case JSON:
+		return typedParameterValue(bigquery.JSONFieldType, string(v)), nil
+	case Geography:
+		return typedParameterValue(bigquery.GeographyFieldType, string(v)), nil
+	case Struct:
+		return v.parameterValue()
+	case *big.Rat:
+		if v == nil {
+			break
+		}
+		return ratParameterValue(v)
+	case *bigquery.IntervalValue:
+		if v == nil {
+			break
+		}
+		return typedParameterValue(bigquery.IntervalFieldType, v.String()), nil
+	case bigquery.IntervalValue:
+		return typedParameterValue(bigquery.IntervalFieldType, v.String()), nil
+	case time.Duration:
+		return typedParameterValue(bigquery.IntervalFieldType, bigquery.IntervalValueFromDuration(v).String()), nil
+	case *bigquery.RangeValue:
+		if v == nil {
+			break
+		}
+		return clientRangeParameterValue(v)
+	case DateRange:
+		return rangeParameterValue(bigquery.DateFieldType, rangeEndpoint(v.Start), rangeEndpoint(v.End)), nil
+	case DateTimeRange:
+		return rangeParameterValue(bigquery.DateTimeFieldType, rangeEndpoint(v.Start), rangeEndpoint(v.End)), nil
+	case TimestampRange:
+		return rangeParameterValue(bigquery.TimestampFieldType, rangeEndpoint(v.Start), rangeEndpoint(v.End)), nil
+	case civil.Date:
+		return typedParameterValue(bigquery.DateFieldType, v.String()), nil
+	case civil.DateTime:
+		return typedParameterValue(bigquery.DateTimeFieldType, bigquery.CivilDateTimeString(v)), nil
+	case civil.Time:
+		return typedParameterValue(bigquery.TimeFieldType, bigquery.CivilTimeString(v)), nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nullParameterValueOf(rv.Type().Elem())
+		}
+		// Valuers with a pointer receiver are called as they are; other pointers are dereferenced.
+		if !rv.Type().Implements(typeOfValuer) || rv.Type().Elem().Implements(typeOfValuer) {
+			return convertParameterValue(rv.Elem().Interface())
+		}
+	case reflect.Slice:
+		if rv.IsNil() && rv.Type().Elem().Kind() == reflect.Uint8 {
+			return nullParameterValueOf(rv.Type())
+		}
+	}
+
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nullParameterValueOf(rv.Type())
+		}
+		return convertParameterValue(v)
+	}
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INT64", u)
+		}
+		return int64(u), nil
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), nil
+	case reflect.Bool:
+		return rv.Bool(), nil
+	case reflect.String:
+		return rv.String(), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+		return arrayParameterValue(rv)
+	case reflect.Array:
+		return arrayParameterValue(rv)
+	case reflect.Struct:
+		if isStructParameterType(rv.Type()) {
+			return structParameterValue(rv)
+		}
+	case reflect.Map:
+		return nil, fmt.Errorf("unsupported parameter type %T; use driver.Struct to send a map as a STRUCT", value)
+	}
+	return nil, fmt.Errorf("unsupported parameter type %T", value)
+}
+
+// decimalParameterValue validates a decimal string and returns it as a typed query parameter value.
+// Only plain decimal numbers such as "-12.5" are accepted, and they must fit the precision and scale
+// of the type, as BigQuery would otherwise round them.
+func decimalParameterValue(fieldType bigquery.FieldType, value string) (*bigquery.QueryParameterValue, error) {
+	r, ok := new(big.Rat).SetString(value)
+	if !ok || !isPlainDecimal(value) {
+		return nil, fmt.Errorf("invalid %s value %q", fieldType, value)
+	}
+	integerDigits, scale := bigquery.NumericPrecisionDigits-bigquery.NumericScaleDigits, bigquery.NumericScaleDigits
+	if fieldType == bigquery.BigNumericFieldType {
+		integerDigits, scale = bigquery.BigNumericPrecisionDigits-bigquery.BigNumericScaleDigits, bigquery.BigNumericScaleDigits
+	}
+	if !fitsDecimal(r, integerDigits, scale) {
+		return nil, fmt.Errorf("%s value %q has more than %d digits before or %d digits after the decimal point", fieldType, value, integerDigits, scale)
+	}
+	return typedParameterValue(fieldType, value), nil
+}
+
+// isPlainDecimal reports whether value is made of an optional sign, digits and an optional
+// decimal point, unlike the fractions, exponents and hexadecimal numbers big.Rat also parses.
+func isPlainDecimal(value string) bool {
+	if strings.HasPrefix(value, "-") || strings.HasPrefix(value, "+") {
+		value = value[1:]
+	}
+	digits, points := 0, 0
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case c == '.':
+			points++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && points <= 1
+}
+
+// ratParameterValue returns r as a NUMERIC value, or as a BIGNUMERIC value when it has more
+// digits than NUMERIC holds. Values that BIGNUMERIC cannot hold exactly either are rejected
+// rather than rounded.
+func ratParameterValue(r *big.Rat) (*bigquery.QueryParameterValue, error) {
+	switch {
+	case fitsDecimal(r, bigquery.NumericPrecisionDigits-bigquery.NumericScaleDigits, bigquery.NumericScaleDigits):
+		return typedParameterValue(bigquery.NumericFieldType, bigquery.NumericString(r)), nil
+	case fitsDecimal(r, bigquery.BigNumericPrecisionDigits-bigquery.BigNumericScaleDigits, bigquery.BigNumericScaleDigits):
+		return typedParameterValue(bigquery.BigNumericFieldType, bigquery.BigNumericString(r)), nil
+	}
+	return nil, fmt.Errorf("%s cannot be sent as NUMERIC or BIGNUMERIC without rounding", r.RatString())
+}
+
+// fitsDecimal reports whether r has at most scale decimal places and integerDigits digits before the point.
+func fitsDecimal(r *big.Rat, integerDigits, scale int) bool {
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !scaled.IsInt() {
+		return false
+	}
+	limit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(integerDigits+scale)), nil)
+	return new(big.Int).Abs(scaled.Num()).Cmp(limit) < 0
+}
+
+func typedParameterValue(fieldType bigquery.FieldType, value string) *bigquery.QueryParameterValue {
+	return &bigquery.QueryParameterValue{
+		Type:  bigquery.StandardSQLDataType{TypeKind: string(fieldType)},
+		Value: value,
+	}
+}
+
+// typedParameterValueOf returns a value converted by convertParameterValue together with its
+// BigQuery type, as required for the elements of ARRAY and the fields of STRUCT parameters.
+func typedParameterValueOf(value interface{}) (bigquery.QueryParameterValue, error) {
+	var fieldType bigquery.FieldType
+	switch v := value.(type) {
+	case *bigquery.QueryParameterValue:
+		return *v, nil
+	case int64, bigquery.NullInt64:
+		fieldType = bigquery.IntegerFieldType
+	case float64, bigquery.NullFloat64:
+		fieldType = bigquery.FloatFieldType
+	case bool, bigquery.NullBool:
+		fieldType = bigquery.BooleanFieldType
+	case string, bigquery.NullString:
+		fieldType = bigquery.StringFieldType
+	case []byte:
+		fieldType = bigquery.BytesFieldType
+	case time.Time, bigquery.NullTimestamp:
+		fieldType = bigquery.TimestampFieldType
+	case bigquery.NullDate:
+		fieldType = bigquery.DateFieldType
+	case bigquery.NullTime:
+		fieldType = bigquery.TimeFieldType
+	case bigquery.NullDateTime:
+		fieldType = bigquery.DateTimeFieldType
+	case bigquery.NullGeography:
+		fieldType = bigquery.GeographyFieldType
+	case bigquery.NullJSON:
+		fieldType = bigquery.JSONFieldType
+	default:
+		return bigquery.QueryParameterValue{}, fmt.Errorf("unsupported element type %T", value)
+	}
+	return bigquery.QueryParameterValue{
+		Type:  bigquery.StandardSQLDataType{TypeKind: standardSQLTypeKind(fieldType)},
+		Value: value,
+	}, nil
+}
+
+// standardSQLTypeKind returns the type kind of query parameters for a field type of the client library.
+func standardSQLTypeKind(fieldType bigquery.FieldType) string {
+	switch fieldType {
+	case bigquery.IntegerFieldType:
+		return "INT64"
+	case bigquery.FloatFieldType:
+		return "FLOAT64"
+	case bigquery.BooleanFieldType:
+		return "BOOL"
+	}
+	return string(fieldType)
+}
+
+// parameterTypeOf infers the BigQuery type of a query parameter from the Go type t,
+// so that empty arrays are typed as well. visiting is nil, or the struct types that contain t.
+func parameterTypeOf(t reflect.Type, visiting map[reflect.Type]bool) (*bigquery.StandardSQLDataType, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isStructParameterType(t) {
+		return structParameterType(t, visiting)
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		fallthrough
+	case reflect.Array:
+		return arrayParameterType(t.Elem(), visiting)
+	case reflect.Interface:
+		return nil, fmt.Errorf("cannot determine the BigQuery type of %s", t)
+	}
+
+	if typed, err := typedParameterValueOf(reflect.Zero(t).Interface()); err == nil {
+		return &typed.Type, nil
+	}
+	null, err := nullParameterValueOf(t)
+	if err != nil {
+		return nil, err
+	}
+	typed, err := typedParameterValueOf(null)
+	if err != nil {
+		return nil, err
+	}
+	return &typed.Type, nil
+}
+
+// arrayParameterType returns the type of an ARRAY whose elements have the Go type t.
+func arrayParameterType(t reflect.Type, visiting map[reflect.Type]bool) (*bigquery.StandardSQLDataType, error) {
+	if isArrayType(t) {
+		return nil, fmt.Errorf("arrays of arrays are not supported: %s", t)
+	}
+	elementType, err := parameterTypeOf(t, visiting)
+	if err != nil {
+		return nil, err
+	}
+	return &bigquery.StandardSQLDataType{ArrayElementType: elementType}, nil
+}
+
+func isArrayType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Array || t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8
+}
+
+// arrayParameterValue converts a slice or an array to an ARRAY query parameter value.
+// The element type is inferred from the Go element type, or from the first element of
+// a slice of interfaces or of Struct values.
+func arrayParameterValue(rv reflect.Value) (*bigquery.QueryParameterValue, error) {
+	elementType := rv.Type().Elem()
+	var arrayType *bigquery.StandardSQLDataType
+	if elementType.Kind() != reflect.Interface && elementType != typeOfStruct {
+		var err error
+		if arrayType, err = arrayParameterType(elementType, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	// The client library sends Value when ArrayValue is empty; an empty slice makes it an empty ARRAY.
+	value := &bigquery.QueryParameterValue{Value: []interface{}{}}
+	for i := 0; i < rv.Len(); i++ {
+		element := rv.Index(i)
+		if (element.Kind() == reflect.Pointer || element.Kind() == reflect.Interface) && element.IsNil() {
+			return nil, fmt.Errorf("element %d is NULL, but arrays cannot contain NULL", i)
+		}
+		converted, err := convertParameterValue(element.Interface())
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		if isArrayType(element.Type()) || element.Kind() == reflect.Interface && isArrayType(element.Elem().Type()) {
+			return nil, fmt.Errorf("arrays of arrays are not supported: %s", rv.Type())
+		}
+		typed, err := typedParameterValueOf(converted)
+		if err != nil {
+			return nil, fmt.Errorf("element %d: %w", i, err)
+		}
+		if arrayType == nil {
+			arrayType = &bigquery.StandardSQLDataType{ArrayElementType: &typed.Type}
+		} else if !reflect.DeepEqual(*arrayType.ArrayElementType, typed.Type) {
+			return nil, fmt.Errorf("element %d has type %s, but the array has elements of type %s",
+				i, typeName(typed.Type), typeName(*arrayType.ArrayElementType))
+		}
+		value.ArrayValue = append(value.ArrayValue, bigquery.QueryParameterValue{
+			Value:       typed.Value,
+			ArrayValue:  typed.ArrayValue,
+			StructValue: typed.StructValue,
+		})
+	}
+	if arrayType == nil {
+		return nil, fmt.Errorf("cannot determine the element type of an empty %s", rv.Type())
+	}
+	value.Type = *arrayType
+	return value, nil
+}
+
+// typeName formats a query parameter type as in GoogleSQL, such as ARRAY<STRUCT<`id` INT64>>.
+// Field names are quoted, as they may be reserved words such as ORDER.
+func typeName(dataType bigquery.StandardSQLDataType) string {
+	switch {
+	case dataType.ArrayElementType != nil:
+		return "ARRAY<" + typeName(*dataType.ArrayElementType) + ">"
+	case dataType.StructType != nil:
+		fields := make([]string, len(dataType.StructType.Fields))
+		for i, field := range dataType.StructType.Fields {
+			fields[i] = quoteIdentifier(field.Name) + " " + typeName(*field.Type)
+		}
+		return "STRUCT<" + strings.Join(fields, ", ") + ">"
+	case dataType.RangeElementType != nil:
+		return "RANGE<" + typeName(*dataType.RangeElementType) + ">"
+	}
+	return dataType.TypeKind
+}
+
+// quoteIdentifier quotes name with backticks, escaping the backticks and backslashes it contains.
+func quoteIdentifier(name string) string {
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(name) + "`"
+}
+
+// checkNamedValue converts a query argument with convertParameterValue for CheckNamedValue.
+// sql.Out arguments are checked with checkOutArgument and left as they are.
+func checkNamedValue(namedValue *driver.NamedValue) error {
+	if out, ok := outOf(namedValue.Value); ok {
+		if err := checkOutArgument(out); err != nil {
+			return fmt.Errorf("invalid parameter %s: %w", parameterName(namedValue.Name, *namedValue), err)
+		}
+		return nil
+	}
+	value, err := convertParameterValue(namedValue.Value)
+	if err != nil {
+		return fmt.Errorf("invalid parameter %s: %w", parameterName(namedValue.Name, *namedValue), err)
+	}
+	namedValue.Value = value
+	return nil
+}
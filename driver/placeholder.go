@@ -0,0 +1,125 @@
+package driver
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// placeholderTranslation is SQL text whose $n or :name placeholders were rewritten to
+// BigQuery's ? and @name, together with what is needed to rearrange the arguments.
+type placeholderTranslation struct {
+	sql string
+	// positions are the argument indexes of the ? placeholders, in order, when $n was used.
+	positions []int
+	// names are the names of the @name placeholders in order of first appearance, when :name was used.
+	names []string
+}
+
+var errMixedTranslatedPlaceholders = errors.New("query mixes $n and :name parameters")
+
+// translatePlaceholders rewrites the $n and :name placeholders of other SQL dialects outside of
+// literals, comments and quoted identifiers. SQL without them is returned unchanged.
+func translatePlaceholders(sql string) (placeholderTranslation, error) {
+	tokens := tokenizeSQL(sql)
+	translation := placeholderTranslation{}
+	seen := make(map[string]bool)
+
+	var builder strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		next := sqlToken{}
+		if i+1 < len(tokens) {
+			next = tokens[i+1]
+		}
+
+		switch {
+		case token.kind == tokenSymbol && token.text == "$" && next.kind == tokenWord && isDigits(next.text):
+			n, err := strconv.Atoi(next.text)
+			if err != nil || n < 1 {
+				return placeholderTranslation{}, fmt.Errorf("invalid parameter $%s", next.text)
+			}
+			translation.positions = append(translation.positions, n-1)
+			builder.WriteString("?")
+			i++
+		case token.kind == tokenSymbol && token.text == ":" && next.kind == tokenWord && isWordStart(next.text[0]) &&
+			(i == 0 || tokens[i-1].kind != tokenWord && tokens[i-1].kind != tokenQuotedIdentifier):
+			// A colon right after an identifier is a label, as in "label: LOOP".
+			if !seen[strings.ToLower(next.text)] {
+				seen[strings.ToLower(next.text)] = true
+				translation.names = append(translation.names, next.text)
+			}
+			builder.WriteString("@" + next.text)
+			i++
+		default:
+			builder.WriteString(token.text)
+		}
+	}
+
+	if len(translation.positions) > 0 && len(translation.names) > 0 {
+		return placeholderTranslation{}, errMixedTranslatedPlaceholders
+	}
+	translation.sql = builder.String()
+	return translation, nil
+}
+
+// numInput returns the number of arguments the translated query takes, or -1 when it is not known.
+func (translation placeholderTranslation) numInput() int {
+	if len(translation.positions) == 0 {
+		return -1
+	}
+	max := 0
+	for _, position := range translation.positions {
+		if position+1 > max {
+			max = position + 1
+		}
+	}
+	return max
+}
+
+// args rearranges args for the translated query: arguments of $n placeholders are repeated
+// and reordered to follow the ? placeholders, and unnamed arguments of :name placeholders
+// are named in order of first appearance.
+func (translation placeholderTranslation) args(args []driver.Value) ([]driver.Value, error) {
+	switch {
+	case len(translation.positions) > 0:
+		translated := make([]driver.Value, 0, len(translation.positions))
+		for i, position := range translation.positions {
+			if position >= len(args) {
+				return nil, fmt.Errorf("no argument was given for the parameter $%d, %d arguments were given", position+1, len(args))
+			}
+			arg := args[position]
+			if namedValue, ok := arg.(driver.NamedValue); ok {
+				namedValue.Ordinal = i + 1
+				arg = namedValue
+			}
+			translated = append(translated, arg)
+		}
+		return translated, nil
+	case len(translation.names) > 0 && len(args) == len(translation.names):
+		translated := make([]driver.Value, 0, len(args))
+		for i, arg := range args {
+			namedValue, ok := arg.(driver.NamedValue)
+			if !ok {
+				namedValue = driver.NamedValue{Ordinal: i + 1, Value: arg}
+			}
+			if namedValue.Name == "" {
+				namedValue.Name = translation.names[i]
+			}
+			translated = append(translated, namedValue)
+		}
+		return translated, nil
+	}
+	return args, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
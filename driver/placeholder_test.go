@@ -0,0 +1,157 @@
+package driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslatePlaceholders(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sql     string
+		want    placeholderTranslation
+		wantErr string
+	}{
+		"numbered": {
+			sql:  "SELECT * FROM t WHERE a = $2 AND b = $1 OR c = $2",
+			want: placeholderTranslation{sql: "SELECT * FROM t WHERE a = ? AND b = ? OR c = ?", positions: []int{1, 0, 1}},
+		},
+		"named": {
+			sql:  "SELECT * FROM t WHERE a=:a AND b = :b OR c = :A",
+			want: placeholderTranslation{sql: "SELECT * FROM t WHERE a=@a AND b = @b OR c = @A", names: []string{"a", "b"}},
+		},
+		"literals and comments": {
+			sql:  "SELECT '$1', \":a\", `$2` -- :b\n, TIME '12:30:00' /* $3 */",
+			want: placeholderTranslation{sql: "SELECT '$1', \":a\", `$2` -- :b\n, TIME '12:30:00' /* $3 */"},
+		},
+		"label": {
+			sql:  "outer_loop: LOOP SELECT :a; END LOOP",
+			want: placeholderTranslation{sql: "outer_loop: LOOP SELECT @a; END LOOP", names: []string{"a"}},
+		},
+		"bigquery placeholders": {
+			sql:  "SELECT ? + @a",
+			want: placeholderTranslation{sql: "SELECT ? + @a"},
+		},
+		"zero": {
+			sql:     "SELECT $0",
+			wantErr: "invalid parameter $0",
+		},
+		"mixed": {
+			sql:     "SELECT $1 + :a",
+			wantErr: errMixedTranslatedPlaceholders.Error(),
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := translatePlaceholders(tt.sql)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlaceholderTranslationArgs(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		translation placeholderTranslation
+		args        []driver.Value
+		want        []driver.Value
+		wantErr     string
+	}{
+		"reordered": {
+			translation: placeholderTranslation{positions: []int{1, 0, 1}},
+			args:        []driver.Value{driver.NamedValue{Ordinal: 1, Value: "a"}, driver.NamedValue{Ordinal: 2, Value: "b"}},
+			want: []driver.Value{
+				driver.NamedValue{Ordinal: 1, Value: "b"},
+				driver.NamedValue{Ordinal: 2, Value: "a"},
+				driver.NamedValue{Ordinal: 3, Value: "b"},
+			},
+		},
+		"missing argument": {
+			translation: placeholderTranslation{positions: []int{0, 2}},
+			args:        []driver.Value{driver.NamedValue{Ordinal: 1, Value: "a"}},
+			wantErr:     "no argument was given for the parameter $3, 1 arguments were given",
+		},
+		"named in order": {
+			translation: placeholderTranslation{names: []string{"a", "b"}},
+			args:        []driver.Value{driver.NamedValue{Ordinal: 1, Value: 1}, 2},
+			want: []driver.Value{
+				driver.NamedValue{Name: "a", Ordinal: 1, Value: 1},
+				driver.NamedValue{Name: "b", Ordinal: 2, Value: 2},
+			},
+		},
+		"already named": {
+			translation: placeholderTranslation{names: []string{"a", "b"}},
+			args:        []driver.Value{driver.NamedValue{Name: "b", Ordinal: 1, Value: 1}, driver.NamedValue{Name: "a", Ordinal: 2, Value: 2}},
+			want:        []driver.Value{driver.NamedValue{Name: "b", Ordinal: 1, Value: 1}, driver.NamedValue{Name: "a", Ordinal: 2, Value: 2}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := tt.translation.args(tt.args)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestBigQueryStatementTranslatePlaceholders(t *testing.T) {
+	t.Parallel()
+
+	var request struct {
+		Query           string `json:"query"`
+		QueryParameters []struct {
+			Name           string         `json:"name"`
+			ParameterValue map[string]any `json:"parameterValue"`
+		} `json:"queryParameters"`
+	}
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&request)
+		writeQueryResponse(w, "1")
+	})
+
+	db, err := sql.Open("bigquery", dsn+"&translate_placeholders=true")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	require.NoError(t, db.QueryRow("SELECT n FROM t WHERE a = $2 AND b = $1", "x", "y").Scan(&n))
+	assert.Equal(t, "SELECT n FROM t WHERE a = ? AND b = ?", request.Query)
+	require.Len(t, request.QueryParameters, 2)
+	assert.Equal(t, "y", request.QueryParameters[0].ParameterValue["value"])
+	assert.Equal(t, "x", request.QueryParameters[1].ParameterValue["value"])
+
+	require.NoError(t, db.QueryRow("SELECT n FROM t WHERE a = :a", sql.Named("a", "z")).Scan(&n))
+	assert.Equal(t, "SELECT n FROM t WHERE a = @a", request.Query)
+	require.Len(t, request.QueryParameters, 1)
+	assert.Equal(t, "a", request.QueryParameters[0].Name)
+
+	stmt, err := db.Prepare("SELECT n FROM t WHERE a = $1 OR b = $1")
+	require.NoError(t, err)
+	t.Cleanup(func() { stmt.Close() })
+	require.NoError(t, stmt.QueryRow("w").Scan(&n))
+	assert.Equal(t, "SELECT n FROM t WHERE a = ? OR b = ?", request.Query)
+	assert.Len(t, request.QueryParameters, 2)
+}
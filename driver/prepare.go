@@ -0,0 +1,177 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// dryRunCacheSize bounds the number of dry-run results a connection keeps.
+const dryRunCacheSize = 128
+
+// PreparedStatement is implemented by the statements returned by Prepare and PrepareContext.
+// Queries without parameters are validated with a dry-run job when they are prepared, so that
+// their result columns and referenced tables are known before they are executed.
+// Queries with parameters are not, as a dry run needs the parameter values; for them the
+// methods below return nothing.
+//
+// Use (*sql.Conn).Raw to get the driver statement:
+//
+//	conn.Raw(func(driverConn any) error {
+//		stmt, err := driverConn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+//		...
+//		columns := stmt.(PreparedStatement).Columns()
+//	})
+type PreparedStatement interface {
+	driver.Stmt
+	// Columns returns the names of the result columns.
+	Columns() []string
+	// ColumnTypeDatabaseTypeName returns the BigQuery type of a result column, such as "STRING".
+	ColumnTypeDatabaseTypeName(index int) string
+	// ColumnTypeNullable reports whether a result column may be NULL.
+	ColumnTypeNullable(index int) (nullable, ok bool)
+	// Schema returns the schema of the result.
+	Schema() bigquery.Schema
+	// ReferencedTables returns the tables the query reads.
+	ReferencedTables() []*bigquery.Table
+}
+
+// dryRun is what a dry-run job found out about a query.
+type dryRun struct {
+	schema           bigquery.Schema
+	columns          bigQuerySchema
+	referencedTables []*bigquery.Table
+}
+
+type bigQueryPreparedStatement struct {
+	*bigQueryStatement
+	dryRun *dryRun
+}
+
+var _ PreparedStatement = (*bigQueryPreparedStatement)(nil)
+
+func (statement *bigQueryPreparedStatement) Columns() []string {
+	if statement.dryRun == nil {
+		return nil
+	}
+	return statement.dryRun.columns.ColumnNames()
+}
+
+func (statement *bigQueryPreparedStatement) ColumnTypeDatabaseTypeName(index int) string {
+	if statement.dryRun == nil {
+		return ""
+	}
+	return statement.dryRun.columns.columnTypes()[index]
+}
+
+func (statement *bigQueryPreparedStatement) ColumnTypeNullable(index int) (bool, bool) {
+	if statement.dryRun == nil {
+		return false, false
+	}
+	return !statement.dryRun.columns.RequiredFlags()[index], true
+}
+
+func (statement *bigQueryPreparedStatement) Schema() bigquery.Schema {
+	if statement.dryRun == nil {
+		return nil
+	}
+	return statement.dryRun.schema
+}
+
+func (statement *bigQueryPreparedStatement) ReferencedTables() []*bigquery.Table {
+	if statement.dryRun == nil {
+		return nil
+	}
+	return statement.dryRun.referencedTables
+}
+
+// dryRunKey identifies the dry-run result of sql: the same text may resolve to other
+// tables in another default dataset, or be refused to another end user.
+func dryRunKey(ctx context.Context, config Config, sql string) string {
+	project, dataset := config.defaultDatasetOf(ctx)
+	identity := ""
+	if credentials := getUserCredentials(ctx); credentials != nil {
+		identity = credentials.identity
+	}
+	return strings.Join([]string{project, dataset, identity, sql}, "\x00")
+}
+
+// dryRunQuery validates the statement with a dry-run job, reusing the result of an earlier
+// dry run of the same query on the connection.
+func (statement *bigQueryStatement) dryRunQuery(ctx context.Context) (*dryRun, error) {
+	connection := statement.connection
+	key := dryRunKey(ctx, connection.config, statement.query)
+	if result, ok := connection.dryRuns.get(key); ok {
+		return result, nil
+	}
+
+	query, err := statement.buildQuery(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	query.DryRun = true
+
+	job, err := query.Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return nil, errors.New("dry run returned no statistics")
+	}
+	if err := status.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &dryRun{}
+	if details, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		result.schema = details.Schema
+		result.referencedTables = details.ReferencedTables
+	}
+	result.columns = createBigQuerySchema(result.schema, nil)
+
+	connection.dryRuns.add(key, result)
+	return result, nil
+}
+
+// dryRunCache is a least recently used cache of dry-run results by dryRunKey.
+// It belongs to a single connection, which database/sql never uses concurrently.
+type dryRunCache struct {
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+type dryRunEntry struct {
+	key    string
+	dryRun *dryRun
+}
+
+func (cache *dryRunCache) get(key string) (*dryRun, bool) {
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*dryRunEntry).dryRun, true
+}
+
+func (cache *dryRunCache) add(key string, result *dryRun) {
+	if cache.entries == nil {
+		cache.entries = make(map[string]*list.Element)
+		cache.order = list.New()
+	}
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*dryRunEntry).dryRun = result
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.order.PushFront(&dryRunEntry{key, result})
+	for cache.order.Len() > dryRunCacheSize {
+		oldest := cache.order.Remove(cache.order.Back()).(*dryRunEntry)
+		delete(cache.entries, oldest.key)
+	}
+}
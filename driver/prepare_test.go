@@ -0,0 +1,132 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigQueryConnectionPrepareContextDryRun(t *testing.T) {
+	t.Parallel()
+
+	var dryRuns atomic.Int32
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		var job struct {
+			Configuration struct {
+				DryRun bool `json:"dryRun"`
+				Query  struct {
+					Query string `json:"query"`
+				} `json:"query"`
+			} `json:"configuration"`
+		}
+		json.NewDecoder(r.Body).Decode(&job)
+		if r.URL.Path != "/projects/project/jobs" || !job.Configuration.DryRun {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "unexpected", http.StatusInternalServerError)
+			return
+		}
+		dryRuns.Add(1)
+
+		if job.Configuration.Query.Query == "SELEC 1" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+				"code": 400, "message": "Syntax error: Unexpected identifier \"SELEC\" at [1:1]",
+				"errors": []map[string]any{{"reason": "invalidQuery", "message": "Syntax error: Unexpected identifier \"SELEC\" at [1:1]"}},
+			}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"jobReference": map[string]string{"projectId": "project", "location": "US"},
+			"status":       map[string]any{"state": "DONE"},
+			"statistics": map[string]any{"query": map[string]any{
+				"statementType": "SELECT",
+				"schema": map[string]any{"fields": []map[string]any{
+					{"name": "id", "type": "INTEGER", "mode": "REQUIRED"},
+					{"name": "tags", "type": "STRING", "mode": "REPEATED"},
+				}},
+				"referencedTables": []map[string]string{{"projectId": "project", "datasetId": "dataset", "tableId": "t"}},
+			}},
+		})
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.Raw(func(driverConn any) error {
+		preparer := driverConn.(driver.ConnPrepareContext)
+
+		for i := 0; i < 2; i++ {
+			stmt, err := preparer.PrepareContext(ctx, "SELECT id, tags FROM t")
+			require.NoError(t, err)
+			prepared := stmt.(PreparedStatement)
+			assert.Equal(t, []string{"id", "tags"}, prepared.Columns())
+			assert.Equal(t, "INTEGER", prepared.ColumnTypeDatabaseTypeName(0))
+			assert.Equal(t, "ARRAY<STRING>", prepared.ColumnTypeDatabaseTypeName(1))
+			nullable, ok := prepared.ColumnTypeNullable(0)
+			assert.True(t, ok)
+			assert.False(t, nullable)
+			assert.Equal(t, bigquery.StringFieldType, prepared.Schema()[1].Type)
+			require.Len(t, prepared.ReferencedTables(), 1)
+			assert.Equal(t, "t", prepared.ReferencedTables()[0].TableID)
+		}
+		assert.Equal(t, int32(1), dryRuns.Load(), "the dry run is cached per query")
+
+		_, err := preparer.PrepareContext(WithDefaultDataset(ctx, "other"), "SELECT id, tags FROM t")
+		require.NoError(t, err)
+		assert.Equal(t, int32(2), dryRuns.Load(), "the dry run depends on the default dataset")
+
+		_, err = preparer.PrepareContext(ctx, "SELEC 1")
+		assert.ErrorContains(t, err, "Syntax error")
+
+		stmt, err := preparer.PrepareContext(ctx, "SELECT id FROM t WHERE id = ?")
+		require.NoError(t, err)
+		assert.Nil(t, stmt.(PreparedStatement).Columns())
+		assert.Equal(t, 1, stmt.NumInput())
+		assert.Equal(t, int32(3), dryRuns.Load(), "queries with parameters are not dry run")
+		return nil
+	}))
+}
+
+func TestDryRunCache(t *testing.T) {
+	t.Parallel()
+
+	var cache dryRunCache
+	results := make([]*dryRun, dryRunCacheSize+1)
+	for i := range results {
+		results[i] = &dryRun{}
+	}
+	for i := 0; i < dryRunCacheSize; i++ {
+		cache.add(strconv.Itoa(i), results[i])
+	}
+	_, ok := cache.get("0")
+	require.True(t, ok)
+
+	cache.add(strconv.Itoa(dryRunCacheSize), results[dryRunCacheSize])
+
+	result, ok := cache.get("0")
+	assert.True(t, ok, "the recently used result is kept")
+	assert.Same(t, results[0], result)
+	_, ok = cache.get("1")
+	assert.False(t, ok, "the least recently used result is evicted")
+	for i := 2; i <= dryRunCacheSize; i++ {
+		result, ok := cache.get(strconv.Itoa(i))
+		require.True(t, ok, "result %d is kept", i)
+		assert.Same(t, results[i], result)
+	}
+}
@@ -0,0 +1,307 @@
+package driver
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// outArgument is a sql.Out argument of a CALL statement, passed to the procedure through a script variable.
+type outArgument struct {
+	out      sql.Out
+	variable string
+}
+
+var errOutArgumentsNotCall = errors.New("sql.Out arguments are only supported by a single CALL statement")
+
+// outOf returns the sql.Out held by a query argument.
+func outOf(arg driver.Value) (sql.Out, bool) {
+	if namedValue, ok := arg.(driver.NamedValue); ok {
+		arg = namedValue.Value
+	}
+	out, ok := arg.(sql.Out)
+	return out, ok
+}
+
+// outDataType returns the type of the variable declared for out, given by its destination.
+func outDataType(out sql.Out) (*bigquery.StandardSQLDataType, error) {
+	rv := reflect.ValueOf(out.Dest)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return nil, fmt.Errorf("the destination of sql.Out must be a non-nil pointer, got %T", out.Dest)
+	}
+	return parameterTypeOf(rv.Type().Elem(), nil)
+}
+
+// outInValue returns the query parameter value of an INOUT argument, the current value of its destination.
+func outInValue(out sql.Out) (interface{}, error) {
+	return convertParameterValue(reflect.ValueOf(out.Dest).Elem().Interface())
+}
+
+// checkOutArgument checks for CheckNamedValue that a variable can be declared for out.
+// The argument is left as it is, as its destination is needed once the procedure returns.
+func checkOutArgument(out sql.Out) error {
+	if _, err := outDataType(out); err != nil {
+		return err
+	}
+	if out.In {
+		if _, err := outInValue(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// callWithOutArguments rewrites a CALL statement with sql.Out arguments into a script that declares
+// a variable for each of them, initialized with the value of INOUT arguments, calls the procedure
+// with the variables in place of the placeholders and selects their values.
+// It returns the script, its arguments and the sql.Out arguments in the order they are selected.
+// Queries without sql.Out arguments are returned unchanged.
+func callWithOutArguments(query string, args []driver.Value) (string, []driver.Value, []outArgument, error) {
+	var callArgs []driver.Value
+	named := make(map[string]driver.Value)
+	for _, arg := range args {
+		if namedValue, ok := arg.(driver.NamedValue); ok && namedValue.Name != "" {
+			named[strings.ToLower(namedValue.Name)] = arg
+		}
+		if _, ok := outOf(arg); !ok {
+			callArgs = append(callArgs, arg)
+		}
+	}
+	if len(callArgs) == len(args) {
+		return query, args, nil, nil
+	}
+
+	tokens := tokenizeSQL(query)
+	significant := significantTokens(tokens)
+	if len(significant) == 0 || significant[0].kind != tokenWord || !strings.EqualFold(significant[0].text, "CALL") {
+		return "", nil, nil, errOutArgumentsNotCall
+	}
+	for i, token := range significant {
+		if token.kind == tokenSymbol && token.text == ";" && i < len(significant)-1 {
+			return "", nil, nil, errOutArgumentsNotCall
+		}
+	}
+
+	var declarations, call strings.Builder
+	var declarationArgs []driver.Value
+	var outs []outArgument
+	declared := make(map[string]bool)
+	positional := 0
+	for _, token := range tokens {
+		var arg driver.Value
+		var variable string
+		switch {
+		case token.kind == tokenComment, token.kind == tokenSymbol && token.text == ";":
+			continue
+		case token.kind == tokenPositional && positional < len(args):
+			arg = args[positional]
+			positional++
+			variable = fmt.Sprintf("_out_%d", positional)
+		case token.kind == tokenNamed:
+			arg = named[strings.ToLower(token.text[1:])]
+			variable = "_out_" + token.text[1:]
+		}
+
+		out, ok := outOf(arg)
+		if !ok {
+			call.WriteString(token.text)
+			continue
+		}
+		call.WriteString(variable)
+		if declared[strings.ToLower(variable)] {
+			continue
+		}
+		declared[strings.ToLower(variable)] = true
+
+		dataType, err := outDataType(out)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("invalid parameter %s: %w", parameterName("", arg), err)
+		}
+		declarations.WriteString("DECLARE " + variable + " " + typeName(*dataType))
+		if out.In {
+			value, err := outInValue(out)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("invalid parameter %s: %w", parameterName("", arg), err)
+			}
+			namedValue, _ := arg.(driver.NamedValue)
+			namedValue.Value = value
+			if token.kind == tokenNamed {
+				declarations.WriteString(" DEFAULT " + token.text)
+			} else {
+				declarations.WriteString(" DEFAULT ?")
+			}
+			declarationArgs = append(declarationArgs, namedValue)
+		}
+		declarations.WriteString(";\n")
+		outs = append(outs, outArgument{out: out, variable: variable})
+	}
+
+	variables := make([]string, len(outs))
+	for i, out := range outs {
+		variables[i] = out.variable
+	}
+	script := declarations.String() + strings.TrimSpace(call.String()) + ";\nSELECT " + strings.Join(variables, ", ") + ";"
+	return script, append(declarationArgs, callArgs...), outs, nil
+}
+
+// assignOutArguments stores the values selected by the script of callWithOutArguments into the
+// destinations of the sql.Out arguments.
+func assignOutArguments(rowIterator *bigquery.RowIterator, outs []outArgument) error {
+	var values []bigquery.Value
+	if err := rowIterator.Next(&values); err != nil {
+		return fmt.Errorf("failed to read the OUT arguments: %w", err)
+	}
+	for i, out := range outs {
+		if i >= len(values) {
+			return fmt.Errorf("no value was returned for the OUT argument %s", out.variable)
+		}
+		if err := assignOutValue(reflect.ValueOf(out.out.Dest).Elem(), values[i]); err != nil {
+			return fmt.Errorf("OUT argument %s: %w", out.variable, err)
+		}
+	}
+	return nil
+}
+
+// assignOutValue stores a value read from BigQuery into dest, converting it to the type of dest.
+func assignOutValue(dest reflect.Value, value bigquery.Value) error {
+	if scanner, ok := dest.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	if value == nil {
+		dest.Set(reflect.Zero(dest.Type()))
+		return nil
+	}
+	if dest.Kind() == reflect.Pointer {
+		elem := reflect.New(dest.Type().Elem())
+		if err := assignOutValue(elem.Elem(), value); err != nil {
+			return err
+		}
+		dest.Set(elem)
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	switch {
+	case rv.Type().AssignableTo(dest.Type()):
+		dest.Set(rv)
+		return nil
+	case rv.Kind() == reflect.Pointer && rv.Elem().Type().AssignableTo(dest.Type()):
+		// NUMERIC, INTERVAL and RANGE values are read as pointers.
+		dest.Set(rv.Elem())
+		return nil
+	}
+
+	switch value := value.(type) {
+	case *big.Rat:
+		switch dest.Kind() {
+		case reflect.String:
+			dest.SetString(decimalString(value))
+			return nil
+		case reflect.Float32, reflect.Float64:
+			f, _ := value.Float64()
+			dest.SetFloat(f)
+			return nil
+		}
+	case *bigquery.IntervalValue:
+		if dest.Type() == typeOfDuration {
+			dest.SetInt(int64(value.ToDuration()))
+			return nil
+		}
+	case *bigquery.RangeValue:
+		switch dest.Type() {
+		case typeOfDateRange, typeOfDateTimeRange, typeOfTimestampRange:
+			if err := assignOutValue(dest.FieldByName("Start"), value.Start); err != nil {
+				return err
+			}
+			return assignOutValue(dest.FieldByName("End"), value.End)
+		}
+	case []bigquery.Value:
+		return assignOutList(dest, value)
+	}
+
+	switch {
+	case isIntKind(rv.Kind()) && isIntKind(dest.Kind()):
+		if dest.OverflowInt(rv.Int()) {
+			return fmt.Errorf("%d overflows %s", rv.Int(), dest.Type())
+		}
+		dest.SetInt(rv.Int())
+		return nil
+	case isIntKind(rv.Kind()) && isUintKind(dest.Kind()):
+		if rv.Int() < 0 || dest.OverflowUint(uint64(rv.Int())) {
+			return fmt.Errorf("%d overflows %s", rv.Int(), dest.Type())
+		}
+		dest.SetUint(uint64(rv.Int()))
+		return nil
+	case (rv.Kind() == reflect.Float64 || isIntKind(rv.Kind())) && (dest.Kind() == reflect.Float32 || dest.Kind() == reflect.Float64),
+		rv.Kind() == reflect.String && dest.Kind() == reflect.String,
+		rv.Kind() == reflect.Bool && dest.Kind() == reflect.Bool:
+		dest.Set(rv.Convert(dest.Type()))
+		return nil
+	}
+	return fmt.Errorf("cannot assign %T to %s", value, dest.Type())
+}
+
+// assignOutList stores the elements of an ARRAY or the fields of a STRUCT into a slice, an array or a struct.
+func assignOutList(dest reflect.Value, values []bigquery.Value) error {
+	switch {
+	case dest.Kind() == reflect.Slice:
+		slice := reflect.MakeSlice(dest.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := assignOutValue(slice.Index(i), value); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		dest.Set(slice)
+		return nil
+	case dest.Kind() == reflect.Array && dest.Len() == len(values):
+		for i, value := range values {
+			if err := assignOutValue(dest.Index(i), value); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		return nil
+	case isStructParameterType(dest.Type()):
+		// The fields are selected in the order of the STRUCT type declared for dest.
+		fields := structFields(dest.Type())
+		if len(fields) != len(values) {
+			return fmt.Errorf("expected %d fields for %s, got %d", len(fields), dest.Type(), len(values))
+		}
+		for i, field := range fields {
+			if err := assignOutValue(dest.FieldByIndex(field.index), values[i]); err != nil {
+				return fmt.Errorf("field %s: %w", field.name, err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("cannot assign %d values to %s", len(values), dest.Type())
+}
+
+// decimalString formats a NUMERIC or BIGNUMERIC value without trailing zeros.
+func decimalString(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return strings.TrimRight(r.FloatString(bigquery.BigNumericScaleDigits), "0")
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
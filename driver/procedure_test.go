@@ -0,0 +1,220 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallWithOutArguments(t *testing.T) {
+	t.Parallel()
+
+	type ordering struct {
+		Order  int64  `bigquery:"order"`
+		Select string `bigquery:"select"`
+	}
+	var count int64
+	var sorting ordering
+	name := "a"
+	tests := map[string]struct {
+		query    string
+		args     []driver.Value
+		want     string
+		wantArgs []driver.Value
+	}{
+		"no out arguments": {
+			query:    "CALL dataset.procedure(?)",
+			args:     []driver.Value{driver.NamedValue{Ordinal: 1, Value: int64(1)}},
+			want:     "CALL dataset.procedure(?)",
+			wantArgs: []driver.Value{driver.NamedValue{Ordinal: 1, Value: int64(1)}},
+		},
+		"positional": {
+			query: "CALL dataset.procedure(?, ?, ?); -- count and rename\n",
+			args: []driver.Value{
+				driver.NamedValue{Ordinal: 1, Value: int64(1)},
+				driver.NamedValue{Ordinal: 2, Value: sql.Out{Dest: &count}},
+				driver.NamedValue{Ordinal: 3, Value: sql.Out{Dest: &name, In: true}},
+			},
+			want: "DECLARE _out_2 INT64;\nDECLARE _out_3 STRING DEFAULT ?;\nCALL dataset.procedure(?, _out_2, _out_3);\nSELECT _out_2, _out_3;",
+			wantArgs: []driver.Value{
+				driver.NamedValue{Ordinal: 3, Value: "a"},
+				driver.NamedValue{Ordinal: 1, Value: int64(1)},
+			},
+		},
+		"named": {
+			query: "call dataset.procedure(@id, @count, @name)",
+			args: []driver.Value{
+				driver.NamedValue{Name: "count", Ordinal: 1, Value: sql.Out{Dest: &count}},
+				driver.NamedValue{Name: "id", Ordinal: 2, Value: int64(1)},
+				driver.NamedValue{Name: "name", Ordinal: 3, Value: sql.Out{Dest: &name, In: true}},
+			},
+			want: "DECLARE _out_count INT64;\nDECLARE _out_name STRING DEFAULT @name;\ncall dataset.procedure(@id, _out_count, _out_name);\nSELECT _out_count, _out_name;",
+			wantArgs: []driver.Value{
+				driver.NamedValue{Name: "name", Ordinal: 3, Value: "a"},
+				driver.NamedValue{Name: "id", Ordinal: 2, Value: int64(1)},
+			},
+		},
+		"struct with reserved field names": {
+			query: "CALL dataset.procedure(?)",
+			args:  []driver.Value{driver.NamedValue{Ordinal: 1, Value: sql.Out{Dest: &sorting}}},
+			want:  "DECLARE _out_1 STRUCT<`order` INT64, `select` STRING>;\nCALL dataset.procedure(_out_1);\nSELECT _out_1;",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, gotArgs, _, err := callWithOutArguments(tt.query, tt.args)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestCallWithOutArgumentsError(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	args := []driver.Value{driver.NamedValue{Ordinal: 1, Value: sql.Out{Dest: &count}}}
+	for _, query := range []string{
+		"SELECT ?",
+		"CALL dataset.procedure(?); SELECT 1",
+	} {
+		_, _, _, err := callWithOutArguments(query, args)
+		assert.ErrorIs(t, err, errOutArgumentsNotCall, query)
+	}
+}
+
+func TestAssignOutValue(t *testing.T) {
+	t.Parallel()
+
+	type record struct {
+		ID   int64  `bigquery:"id"`
+		Name string `bigquery:"name"`
+	}
+	date := civil.Date{Year: 2024, Month: 1, Day: 2}
+	tests := map[string]struct {
+		value bigquery.Value
+		dest  any
+		want  any
+	}{
+		"int64":          {value: int64(1), dest: new(int64), want: int64(1)},
+		"int":            {value: int64(1), dest: new(int), want: 1},
+		"pointer":        {value: "a", dest: new(*string), want: func() *string { s := "a"; return &s }()},
+		"nil pointer":    {value: nil, dest: new(*string), want: (*string)(nil)},
+		"scanner":        {value: int64(1), dest: new(sql.NullInt64), want: sql.NullInt64{Int64: 1, Valid: true}},
+		"scanner null":   {value: nil, dest: new(sql.NullString), want: sql.NullString{}},
+		"date":           {value: date, dest: new(civil.Date), want: date},
+		"numeric":        {value: big.NewRat(3, 2), dest: new(big.Rat), want: *big.NewRat(3, 2)},
+		"numeric string": {value: big.NewRat(3, 2), dest: new(Numeric), want: Numeric("1.5")},
+		"interval":       {value: &bigquery.IntervalValue{Minutes: 2}, dest: new(time.Duration), want: 2 * time.Minute},
+		"array":          {value: []bigquery.Value{int64(1), int64(2)}, dest: new([]int64), want: []int64{1, 2}},
+		"struct":         {value: []bigquery.Value{int64(1), "a"}, dest: new(record), want: record{ID: 1, Name: "a"}},
+		"date range":     {value: &bigquery.RangeValue{Start: date}, dest: new(DateRange), want: DateRange{Start: &date}},
+		"bool":           {value: true, dest: new(bool), want: true},
+		"float from rat": {value: big.NewRat(1, 4), dest: new(float64), want: 0.25},
+		"bytes":          {value: []byte("a"), dest: new([]byte), want: []byte("a")},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dest := reflect.ValueOf(tt.dest).Elem()
+			require.NoError(t, assignOutValue(dest, tt.value))
+			assert.Equal(t, tt.want, dest.Interface())
+		})
+	}
+}
+
+func TestAssignOutValueError(t *testing.T) {
+	t.Parallel()
+
+	assert.EqualError(t, assignOutValue(reflect.ValueOf(new(int8)).Elem(), int64(1000)), "1000 overflows int8")
+	assert.EqualError(t, assignOutValue(reflect.ValueOf(new(string)).Elem(), int64(1)), "cannot assign int64 to string")
+}
+
+func TestBigQueryStatementCheckNamedValueOut(t *testing.T) {
+	t.Parallel()
+
+	var count int64
+	namedValue := driver.NamedValue{Ordinal: 1, Value: sql.Out{Dest: &count}}
+	require.NoError(t, bigQueryStatement{}.CheckNamedValue(&namedValue))
+	assert.Equal(t, sql.Out{Dest: &count}, namedValue.Value)
+
+	namedValue = driver.NamedValue{Ordinal: 1, Value: sql.Out{Dest: count}}
+	assert.EqualError(t, bigQueryStatement{}.CheckNamedValue(&namedValue), "invalid parameter 1: the destination of sql.Out must be a non-nil pointer, got int64")
+
+	var values []any
+	namedValue = driver.NamedValue{Name: "values", Value: sql.Out{Dest: &values}}
+	assert.EqualError(t, bigQueryStatement{}.CheckNamedValue(&namedValue), "invalid parameter @values: cannot determine the BigQuery type of interface {}")
+}
+
+func TestBigQueryStatementExecContextOutArguments(t *testing.T) {
+	t.Parallel()
+
+	var request struct {
+		Query           string `json:"query"`
+		QueryParameters []struct {
+			ParameterValue map[string]any `json:"parameterValue"`
+		} `json:"queryParameters"`
+	}
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/project/jobs/job":
+			writeJobStatistics(w, map[string]any{"statementType": "SCRIPT"})
+			return
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/project/jobs":
+			json.NewEncoder(w).Encode(map[string]any{"jobs": []any{}})
+			return
+		case r.Method != http.MethodPost || r.URL.Path != "/projects/project/queries":
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		json.NewEncoder(w).Encode(map[string]any{
+			"jobComplete":  true,
+			"jobReference": map[string]string{"projectId": "project", "jobId": "job", "location": "US"},
+			"schema": map[string]any{"fields": []map[string]string{
+				{"name": "_out_count", "type": "INTEGER"},
+				{"name": "_out_name", "type": "STRING"},
+			}},
+			"totalRows": "1",
+			"rows":      []map[string]any{{"f": []map[string]any{{"v": "2"}, {"v": "b"}}}},
+		})
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var count int64
+	name := "a"
+	_, err = db.ExecContext(context.Background(), "CALL dataset.procedure(@id, @count, @name)",
+		sql.Named("id", 1), sql.Named("count", sql.Out{Dest: &count}), sql.Named("name", sql.Out{Dest: &name, In: true}))
+	require.NoError(t, err)
+
+	assert.Equal(t, "DECLARE _out_count INT64;\nDECLARE _out_name STRING DEFAULT @name;\nCALL dataset.procedure(@id, _out_count, _out_name);\nSELECT _out_count, _out_name;", request.Query)
+	require.Len(t, request.QueryParameters, 2)
+	assert.Equal(t, "a", request.QueryParameters[0].ParameterValue["value"])
+	assert.Equal(t, "1", request.QueryParameters[1].ParameterValue["value"])
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, "b", name)
+
+	_, err = db.QueryContext(context.Background(), "CALL dataset.procedure(@count)", sql.Named("count", sql.Out{Dest: &count}))
+	assert.ErrorIs(t, err, errOutArgumentsNotExec)
+}
@@ -0,0 +1,113 @@
+package driver
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+)
+
+var queryOptionsCtxKey = struct{ value string }{"queryOptionsCtxKey"}
+
+// QueryOption changes the job settings of a single query.
+// It is applied on top of the connection defaults.
+type QueryOption func(query *bigquery.Query)
+
+// WithJobIDPrefix makes the job ID start with the prefix followed by a random suffix.
+func WithJobIDPrefix(prefix string) QueryOption {
+	return func(query *bigquery.Query) {
+		query.JobID = prefix
+		query.AddJobIDSuffix = true
+	}
+}
+
+// WithLabels adds labels to the job. They override connection labels with the same key.
+func WithLabels(labels map[string]string) QueryOption {
+	return func(query *bigquery.Query) {
+		if query.Labels == nil {
+			query.Labels = make(map[string]string, len(labels))
+		}
+		for key, value := range labels {
+			query.Labels[key] = value
+		}
+	}
+}
+
+// WithMaxBytesBilled limits the bytes billed for the job.
+func WithMaxBytesBilled(maxBytesBilled int64) QueryOption {
+	return func(query *bigquery.Query) {
+		query.MaxBytesBilled = maxBytesBilled
+	}
+}
+
+// WithPriority sets the priority of the job.
+func WithPriority(priority bigquery.QueryPriority) QueryOption {
+	return func(query *bigquery.Query) {
+		query.Priority = priority
+	}
+}
+
+// WithJobTimeout sets the timeout after which BigQuery cancels the job.
+func WithJobTimeout(timeout time.Duration) QueryOption {
+	return func(query *bigquery.Query) {
+		query.JobTimeout = timeout
+	}
+}
+
+// WithQueryCache enables or disables the query cache for the job.
+func WithQueryCache(enabled bool) QueryOption {
+	return func(query *bigquery.Query) {
+		query.DisableQueryCache = !enabled
+	}
+}
+
+// WithConnectionProperty sets a connection property for the job, replacing the connection default.
+// Only the properties listed by SupportedConnectionProperties are accepted; others make the query fail before it is submitted.
+func WithConnectionProperty(key string, value string) QueryOption {
+	return func(query *bigquery.Query) {
+		for _, property := range query.ConnectionProperties {
+			if property.Key == key {
+				property.Value = value
+				return
+			}
+		}
+		query.ConnectionProperties = append(query.ConnectionProperties, &bigquery.ConnectionProperty{Key: key, Value: value})
+	}
+}
+
+// WithJobCreationRequired makes the query create a job even when the connection runs
+// short queries without one, like WithJobCreationMode with bigquery.JobCreationModeRequired.
+func WithJobCreationRequired() QueryOption {
+	return requireJobCreation
+}
+
+// WithQueryOptions returns a context that carries the options for queries executed with it.
+// Options already set on ctx are kept and applied first.
+//
+//	ctx = driver.WithQueryOptions(ctx, driver.WithPriority(bigquery.BatchPriority))
+//	db.WithContext(ctx).Find(&records)
+func WithQueryOptions(ctx context.Context, opts ...QueryOption) context.Context {
+	existing := getQueryOptions(ctx)
+	merged := make([]QueryOption, 0, len(existing)+len(opts))
+	merged = append(merged, existing...)
+	merged = append(merged, opts...)
+	return context.WithValue(ctx, queryOptionsCtxKey, merged)
+}
+
+func getQueryOptions(ctx context.Context) []QueryOption {
+	if ctx == nil {
+		return nil
+	}
+
+	value := ctx.Value(queryOptionsCtxKey)
+	if value == nil {
+		return nil
+	}
+	return value.([]QueryOption)
+}
+
+func applyQueryOptions(ctx context.Context, query *bigquery.Query) {
+	for _, opt := range getQueryOptions(ctx) {
+		opt(query)
+	}
+}
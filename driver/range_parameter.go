@@ -0,0 +1,87 @@
+package driver
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+)
+
+// DateRange is sent as a RANGE<DATE> query parameter. A nil Start or End is unbounded.
+type DateRange struct {
+	Start *civil.Date
+	End   *civil.Date
+}
+
+// DateTimeRange is sent as a RANGE<DATETIME> query parameter. A nil Start or End is unbounded.
+type DateTimeRange struct {
+	Start *civil.DateTime
+	End   *civil.DateTime
+}
+
+// TimestampRange is sent as a RANGE<TIMESTAMP> query parameter. A nil Start or End is unbounded.
+type TimestampRange struct {
+	Start *time.Time
+	End   *time.Time
+}
+
+var (
+	typeOfDateRange      = reflect.TypeOf(DateRange{})
+	typeOfDateTimeRange  = reflect.TypeOf(DateTimeRange{})
+	typeOfTimestampRange = reflect.TypeOf(TimestampRange{})
+)
+
+// rangeParameterType returns the type of a RANGE with elements of elementType.
+func rangeParameterType(elementType bigquery.FieldType) bigquery.StandardSQLDataType {
+	return bigquery.StandardSQLDataType{
+		TypeKind:         string(bigquery.RangeFieldType),
+		RangeElementType: &bigquery.StandardSQLDataType{TypeKind: string(elementType)},
+	}
+}
+
+// rangeParameterValue returns a RANGE query parameter value. Nil endpoints are unbounded.
+func rangeParameterValue(elementType bigquery.FieldType, start, end interface{}) *bigquery.QueryParameterValue {
+	return &bigquery.QueryParameterValue{
+		Type:  rangeParameterType(elementType),
+		Value: &bigquery.RangeValue{Start: start, End: end},
+	}
+}
+
+// rangeEndpoint dereferences an endpoint of a range wrapper, returning nil for an unbounded endpoint.
+func rangeEndpoint[T any](endpoint *T) interface{} {
+	if endpoint == nil {
+		return nil
+	}
+	return *endpoint
+}
+
+// clientRangeParameterValue types a RangeValue of the client library by its endpoints,
+// which must be civil.Date, civil.DateTime or time.Time values.
+func clientRangeParameterValue(value *bigquery.RangeValue) (*bigquery.QueryParameterValue, error) {
+	var elementType bigquery.FieldType
+	for _, endpoint := range []interface{}{value.Start, value.End} {
+		var endpointType bigquery.FieldType
+		switch endpoint.(type) {
+		case nil:
+			continue
+		case civil.Date:
+			endpointType = bigquery.DateFieldType
+		case civil.DateTime:
+			endpointType = bigquery.DateTimeFieldType
+		case time.Time:
+			endpointType = bigquery.TimestampFieldType
+		default:
+			return nil, fmt.Errorf("unsupported range endpoint type %T", endpoint)
+		}
+		if elementType != "" && elementType != endpointType {
+			return nil, fmt.Errorf("range endpoints have different types %s and %s", elementType, endpointType)
+		}
+		elementType = endpointType
+	}
+	if elementType == "" {
+		return nil, fmt.Errorf("cannot determine the element type of an unbounded RangeValue; use driver.DateRange, driver.DateTimeRange or driver.TimestampRange")
+	}
+	return rangeParameterValue(elementType, value.Start, value.End), nil
+}
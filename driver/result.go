@@ -1,19 +1,124 @@
 package driver
 
 import (
+	"context"
+	"database/sql/driver"
 	"errors"
 
 	"cloud.google.com/go/bigquery"
 )
 
+// DMLResult is implemented by the driver.Result of a statement with the statistics BigQuery
+// reports for it. database/sql hides it behind sql.Result, so it is reached by running the
+// statement on the driver connection with (*sql.Conn).Raw:
+//
+//	err := conn.Raw(func(driverConn any) error {
+//		result, err := driverConn.(driver.ExecerContext).ExecContext(ctx, "UPDATE t SET a = 1 WHERE true", nil)
+//		if err != nil {
+//			return err
+//		}
+//		stats := result.(bigquerydriver.DMLResult).DMLStats()
+//		...
+//	})
+type DMLResult interface {
+	driver.Result
+	// StatementType is the type of the statement, such as "INSERT", "MERGE", "CREATE_TABLE" or "SCRIPT".
+	// It is empty when BigQuery ran the statement without a job.
+	StatementType() string
+	// DMLStats returns the number of rows a DML statement, or the DML statements of a script,
+	// inserted, updated and deleted. It returns nil for other statements.
+	DMLStats() *bigquery.DMLStatistics
+	// DDLTargetTable returns the table a DDL statement created, changed or dropped, or nil.
+	DDLTargetTable() *bigquery.Table
+	// DDLOperationPerformed is the operation a DDL statement performed on its target,
+	// such as "CREATE", "SKIP", "REPLACE" or "DROP".
+	DDLOperationPerformed() string
+}
+
+var _ DMLResult = (*bigQueryResult)(nil)
+
 type bigQueryResult struct {
-	rowIterator *bigquery.RowIterator
+	statementType         string
+	numDMLAffectedRows    int64
+	dmlStats              *bigquery.DMLStatistics
+	ddlTargetTable        *bigquery.Table
+	ddlOperationPerformed string
+}
+
+// newResult reads the statistics of the job of a statement. Scripts add up the statistics of their
+// DML statements, which are reported to the handler given with WithScriptStatements.
+// Statements that BigQuery ran without a job are queries, which affect no rows.
+func newResult(ctx context.Context, rowIterator *bigquery.RowIterator) (*bigQueryResult, error) {
+	job := rowIterator.SourceJob()
+	if job == nil {
+		return &bigQueryResult{}, nil
+	}
+	status, err := job.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &bigQueryResult{}
+	if status.Statistics == nil {
+		return result, nil
+	}
+	if details, ok := status.Statistics.Details.(*bigquery.QueryStatistics); ok {
+		result.statementType = details.StatementType
+		result.numDMLAffectedRows = details.NumDMLAffectedRows
+		result.dmlStats = details.DMLStats
+		result.ddlTargetTable = details.DDLTargetTable
+		result.ddlOperationPerformed = details.DDLOperationPerformed
+	}
+	if result.statementType != "SCRIPT" {
+		return result, nil
+	}
+
+	statements, _, err := scriptChildJobs(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if handler := getScriptStatementsHandler(ctx); handler != nil {
+		handler(statements)
+	}
+	// The statistics of the script job are not added, as they would count its statements twice.
+	result.numDMLAffectedRows, result.dmlStats = 0, nil
+	for _, statement := range statements {
+		result.numDMLAffectedRows += statement.NumDMLAffectedRows
+		if statement.DMLStats == nil {
+			continue
+		}
+		if result.dmlStats == nil {
+			result.dmlStats = &bigquery.DMLStatistics{}
+		}
+		result.dmlStats.InsertedRowCount += statement.DMLStats.InsertedRowCount
+		result.dmlStats.UpdatedRowCount += statement.DMLStats.UpdatedRowCount
+		result.dmlStats.DeletedRowCount += statement.DMLStats.DeletedRowCount
+	}
+	return result, nil
 }
 
 func (result *bigQueryResult) LastInsertId() (int64, error) {
 	return 0, errors.New("LastInsertId is not supported")
 }
 
+// RowsAffected returns the number of rows a DML statement, or the DML statements of a script,
+// changed. It returns 0 for other statements.
 func (result *bigQueryResult) RowsAffected() (int64, error) {
-	return int64(result.rowIterator.TotalRows), nil
+	return result.numDMLAffectedRows, nil
+}
+
+func (result *bigQueryResult) StatementType() string {
+	return result.statementType
+}
+
+func (result *bigQueryResult) DMLStats() *bigquery.DMLStatistics {
+	return result.dmlStats
+}
+
+func (result *bigQueryResult) DDLTargetTable() *bigquery.Table {
+	return result.ddlTargetTable
+}
+
+func (result *bigQueryResult) DDLOperationPerformed() string {
+	return result.ddlOperationPerformed
 }
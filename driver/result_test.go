@@ -0,0 +1,144 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigQueryResult(t *testing.T) {
+	t.Parallel()
+
+	scriptChildren := []map[string]any{
+		{
+			"jobReference": map[string]string{"projectId": "project", "jobId": "delete", "location": "US"},
+			"statistics": map[string]any{
+				"creationTime":     "2000",
+				"query":            map[string]any{"statementType": "DELETE", "numDmlAffectedRows": "1", "dmlStats": map[string]string{"deletedRowCount": "1"}},
+				"scriptStatistics": map[string]any{"evaluationKind": "STATEMENT"},
+			},
+			"status": map[string]any{"state": "DONE"},
+		},
+		{
+			"jobReference": map[string]string{"projectId": "project", "jobId": "insert", "location": "US"},
+			"statistics": map[string]any{
+				"creationTime":     "1000",
+				"query":            map[string]any{"statementType": "INSERT", "numDmlAffectedRows": "2", "dmlStats": map[string]string{"insertedRowCount": "2"}},
+				"scriptStatistics": map[string]any{"evaluationKind": "STATEMENT"},
+			},
+			"status": map[string]any{"state": "DONE"},
+		},
+	}
+
+	tests := map[string]struct {
+		statistics         map[string]any
+		withoutJob         bool
+		wantRowsAffected   int64
+		wantStatementType  string
+		wantDMLStats       *bigquery.DMLStatistics
+		wantDDLTargetTable string
+		wantDDLOperation   string
+	}{
+		"update": {
+			statistics:        map[string]any{"statementType": "UPDATE", "numDmlAffectedRows": "3", "dmlStats": map[string]string{"updatedRowCount": "3"}},
+			wantRowsAffected:  3,
+			wantStatementType: "UPDATE",
+			wantDMLStats:      &bigquery.DMLStatistics{UpdatedRowCount: 3},
+		},
+		"merge": {
+			statistics:        map[string]any{"statementType": "MERGE", "numDmlAffectedRows": "5", "dmlStats": map[string]string{"insertedRowCount": "2", "updatedRowCount": "3"}},
+			wantRowsAffected:  5,
+			wantStatementType: "MERGE",
+			wantDMLStats:      &bigquery.DMLStatistics{InsertedRowCount: 2, UpdatedRowCount: 3},
+		},
+		"ddl": {
+			statistics: map[string]any{
+				"statementType":         "CREATE_TABLE",
+				"ddlOperationPerformed": "CREATE",
+				"ddlTargetTable":        map[string]string{"projectId": "project", "datasetId": "dataset", "tableId": "t"},
+			},
+			wantStatementType:  "CREATE_TABLE",
+			wantDDLTargetTable: "t",
+			wantDDLOperation:   "CREATE",
+		},
+		"script": {
+			statistics:        map[string]any{"statementType": "SCRIPT"},
+			wantRowsAffected:  3,
+			wantStatementType: "SCRIPT",
+			wantDMLStats:      &bigquery.DMLStatistics{InsertedRowCount: 2, DeletedRowCount: 1},
+		},
+		"script with totals": {
+			statistics:        map[string]any{"statementType": "SCRIPT", "numDmlAffectedRows": "3", "dmlStats": map[string]string{"insertedRowCount": "2", "deletedRowCount": "1"}},
+			wantRowsAffected:  3,
+			wantStatementType: "SCRIPT",
+			wantDMLStats:      &bigquery.DMLStatistics{InsertedRowCount: 2, DeletedRowCount: 1},
+		},
+		"without job": {
+			withoutJob: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/projects/project/queries":
+					response := map[string]any{"jobComplete": true, "totalRows": "0"}
+					if !tt.withoutJob {
+						response["jobReference"] = map[string]string{"projectId": "project", "jobId": "job", "location": "US"}
+					}
+					json.NewEncoder(w).Encode(response)
+				case r.Method == http.MethodGet && r.URL.Path == "/projects/project/jobs/job":
+					writeJobStatistics(w, tt.statistics)
+				case r.Method == http.MethodGet && r.URL.Path == "/projects/project/jobs":
+					assert.Equal(t, "job", r.URL.Query().Get("parentJobId"))
+					json.NewEncoder(w).Encode(map[string]any{"jobs": scriptChildren})
+				default:
+					t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+					http.NotFound(w, r)
+				}
+			})
+
+			db, err := sql.Open("bigquery", dsn)
+			require.NoError(t, err)
+			t.Cleanup(func() { db.Close() })
+
+			result, err := db.ExecContext(context.Background(), "UPDATE t SET a = 1 WHERE true")
+			require.NoError(t, err)
+			rowsAffected, err := result.RowsAffected()
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRowsAffected, rowsAffected)
+
+			conn, err := db.Conn(context.Background())
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+
+			require.NoError(t, conn.Raw(func(driverConn any) error {
+				result, err := driverConn.(driver.ExecerContext).ExecContext(context.Background(), "UPDATE t SET a = 1 WHERE true", nil)
+				if err != nil {
+					return err
+				}
+				dmlResult := result.(DMLResult)
+				assert.Equal(t, tt.wantStatementType, dmlResult.StatementType())
+				assert.Equal(t, tt.wantDMLStats, dmlResult.DMLStats())
+				assert.Equal(t, tt.wantDDLOperation, dmlResult.DDLOperationPerformed())
+				if tt.wantDDLTargetTable == "" {
+					assert.Nil(t, dmlResult.DDLTargetTable())
+				} else {
+					require.NotNil(t, dmlResult.DDLTargetTable())
+					assert.Equal(t, tt.wantDDLTargetTable, dmlResult.DDLTargetTable().TableID)
+				}
+				return nil
+			}))
+		})
+	}
+}
@@ -17,6 +17,9 @@ type bigQueryRows struct {
 	source  bigQuerySource
 	schema  bigQuerySchema
 	adaptor adaptor.SchemaAdaptor
+	closed  bool
+	// script holds the remaining result sets of a script.
+	script *scriptResultSets
 }
 
 func (rows *bigQueryRows) ensureSchema() {
@@ -30,11 +33,23 @@ func (rows *bigQueryRows) Columns() []string {
 	return rows.schema.ColumnNames()
 }
 
+// Close stops the iteration and releases the source, so that no more pages are fetched.
 func (rows *bigQueryRows) Close() error {
+	if rows.closed {
+		return nil
+	}
+	if rows.source != nil {
+		rows.ensureSchema()
+	}
+	rows.closed = true
+	rows.source = nil
 	return nil
 }
 
 func (rows *bigQueryRows) Next(dest []driver.Value) error {
+	if rows.closed {
+		return io.EOF
+	}
 
 	rows.ensureSchema()
 
@@ -88,6 +103,27 @@ func convertBaseMachinaUnsupportedValueToString(value driver.Value) (string, boo
 	return "", false
 }
 
+var _ driver.RowsNextResultSet = (*bigQueryRows)(nil)
+
+// HasNextResultSet reports whether a script has more SELECT statements with rows.
+func (rows *bigQueryRows) HasNextResultSet() bool {
+	return !rows.closed && rows.script != nil && rows.script.hasNext()
+}
+
+// NextResultSet moves to the rows of the next SELECT statement of a script.
+func (rows *bigQueryRows) NextResultSet() error {
+	if !rows.HasNextResultSet() {
+		return io.EOF
+	}
+	source, err := rows.script.nextSource()
+	if err != nil {
+		return err
+	}
+	rows.source = source
+	rows.schema = nil
+	return nil
+}
+
 var _ driver.RowsColumnTypeDatabaseTypeName = (*bigQueryRows)(nil)
 
 func (rows *bigQueryRows) ColumnTypeDatabaseTypeName(index int) string {
@@ -0,0 +1,178 @@
+package driver
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/basemachina/go-bigquery/adaptor"
+	"google.golang.org/api/iterator"
+)
+
+// ScriptStatement describes a statement of a script, which BigQuery runs as a child job of the script job.
+type ScriptStatement struct {
+	JobID string
+	// StatementType is the type of the statement, such as "SELECT" or "INSERT".
+	StatementType string
+	// Text is the text of the statement, and StartLine to EndColumn its 1-based position in the script.
+	Text        string
+	StartLine   int64
+	StartColumn int64
+	EndLine     int64
+	EndColumn   int64
+	// NumDMLAffectedRows is the number of rows a DML statement changed.
+	NumDMLAffectedRows int64
+	// DMLStats are the number of rows a DML statement inserted, updated and deleted.
+	DMLStats            *bigquery.DMLStatistics
+	TotalBytesProcessed int64
+	// ResultSet is the index of the result set of a SELECT statement, as walked by
+	// (*sql.Rows).NextResultSet, or -1 when the statement returns no rows.
+	ResultSet int
+}
+
+var scriptStatementsCtxKey = struct{ value string }{"scriptStatementsCtxKey"}
+
+// WithScriptStatements returns a context that reports the statements of scripts run with it
+// to handler, in the order they ran.
+func WithScriptStatements(ctx context.Context, handler func([]ScriptStatement)) context.Context {
+	return context.WithValue(ctx, scriptStatementsCtxKey, handler)
+}
+
+func getScriptStatementsHandler(ctx context.Context) func([]ScriptStatement) {
+	if ctx == nil {
+		return nil
+	}
+
+	value := ctx.Value(scriptStatementsCtxKey)
+	if value == nil {
+		return nil
+	}
+	return value.(func([]ScriptStatement))
+}
+
+// scriptKeywords are the keywords that start a script rather than a single statement.
+var scriptKeywords = map[string]bool{
+	"DECLARE": true, "SET": true, "BEGIN": true, "IF": true, "LOOP": true, "WHILE": true,
+	"REPEAT": true, "FOR": true, "CALL": true, "EXECUTE": true,
+}
+
+// isScript reports whether sql is a script: several statements, or a scripting statement.
+// Temporary functions defined before a single statement do not make a script, as they
+// return no rows.
+func isScript(sql string) bool {
+	var statements [][]sqlToken
+	var statement []sqlToken
+	for _, token := range significantTokens(tokenizeSQL(sql)) {
+		if token.kind != tokenSymbol || token.text != ";" {
+			statement = append(statement, token)
+			continue
+		}
+		if len(statement) > 0 && !isTempFunctionDefinition(statement) {
+			statements = append(statements, statement)
+		}
+		statement = nil
+	}
+	if len(statement) > 0 && !isTempFunctionDefinition(statement) {
+		statements = append(statements, statement)
+	}
+
+	if len(statements) == 0 {
+		return false
+	}
+	first := statements[0][0]
+	return len(statements) > 1 || first.kind == tokenWord && scriptKeywords[strings.ToUpper(first.text)]
+}
+
+// isTempFunctionDefinition reports whether the tokens of a statement define a temporary function.
+func isTempFunctionDefinition(statement []sqlToken) bool {
+	var words []string
+	for _, token := range statement {
+		if len(words) == 5 {
+			break
+		}
+		words = append(words, strings.ToUpper(token.text))
+	}
+	if len(words) > 2 && words[0] == "CREATE" && words[1] == "OR" && words[2] == "REPLACE" {
+		words = append([]string{"CREATE"}, words[3:]...)
+	}
+	return len(words) > 2 && words[0] == "CREATE" && (words[1] == "TEMP" || words[1] == "TEMPORARY") && words[2] == "FUNCTION"
+}
+
+// scriptChildJobs returns the statements run by a script job, in the order they ran,
+// and the jobs of those that returned rows.
+func scriptChildJobs(ctx context.Context, job *bigquery.Job) ([]ScriptStatement, []*bigquery.Job, error) {
+	var children []*bigquery.Job
+	it := job.Children(ctx)
+	for {
+		child, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if child.LastStatus() != nil && child.LastStatus().Statistics != nil {
+			children = append(children, child)
+		}
+	}
+	// Jobs are listed from the most recent one.
+	sort.SliceStable(children, func(i, j int) bool {
+		a, b := children[i].LastStatus().Statistics, children[j].LastStatus().Statistics
+		if !a.CreationTime.Equal(b.CreationTime) {
+			return a.CreationTime.Before(b.CreationTime)
+		}
+		return a.StartTime.Before(b.StartTime)
+	})
+
+	var statements []ScriptStatement
+	var resultJobs []*bigquery.Job
+	for _, child := range children {
+		statistics := child.LastStatus().Statistics
+		// Expressions are evaluated by child jobs too, such as the condition of an IF.
+		if statistics.ScriptStatistics != nil && statistics.ScriptStatistics.EvaluationKind == "EXPRESSION" {
+			continue
+		}
+
+		statement := ScriptStatement{JobID: child.ID(), TotalBytesProcessed: statistics.TotalBytesProcessed, ResultSet: -1}
+		if details, ok := statistics.Details.(*bigquery.QueryStatistics); ok {
+			statement.StatementType = details.StatementType
+			statement.NumDMLAffectedRows = details.NumDMLAffectedRows
+			statement.DMLStats = details.DMLStats
+		}
+		if statistics.ScriptStatistics != nil && len(statistics.ScriptStatistics.StackFrames) > 0 {
+			frame := statistics.ScriptStatistics.StackFrames[0]
+			statement.Text = frame.Text
+			statement.StartLine, statement.StartColumn = frame.StartLine, frame.StartColumn
+			statement.EndLine, statement.EndColumn = frame.EndLine, frame.EndColumn
+		}
+		if statement.StatementType == "SELECT" {
+			statement.ResultSet = len(resultJobs)
+			resultJobs = append(resultJobs, child)
+		}
+		statements = append(statements, statement)
+	}
+	return statements, resultJobs, nil
+}
+
+// scriptResultSets are the result sets of the SELECT statements of a script, read one
+// after the other from their child jobs.
+type scriptResultSets struct {
+	ctx           context.Context
+	jobs          []*bigquery.Job
+	next          int
+	schemaAdaptor adaptor.SchemaAdaptor
+}
+
+func (resultSets *scriptResultSets) hasNext() bool {
+	return resultSets.next < len(resultSets.jobs)
+}
+
+func (resultSets *scriptResultSets) nextSource() (bigQuerySource, error) {
+	rowIterator, err := resultSets.jobs[resultSets.next].Read(resultSets.ctx)
+	if err != nil {
+		return nil, err
+	}
+	resultSets.next++
+	return createSourceFromRowIterator(rowIterator, resultSets.schemaAdaptor), nil
+}
@@ -0,0 +1,144 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsScript(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sql  string
+		want bool
+	}{
+		"select":                         {sql: "SELECT 1", want: false},
+		"trailing semicolon":             {sql: "SELECT 1;\n", want: false},
+		"semicolon in literal":           {sql: "SELECT ';' -- ;\n", want: false},
+		"multiple statements":            {sql: "SELECT 1; SELECT 2", want: true},
+		"declare":                        {sql: "DECLARE x INT64", want: true},
+		"call":                           {sql: "CALL dataset.procedure()", want: true},
+		"begin":                          {sql: "/* script */ BEGIN SELECT 1; END", want: true},
+		"insert":                         {sql: "INSERT INTO t (a) VALUES (1)", want: false},
+		"keyword as an identifier":       {sql: "SELECT `set` FROM t", want: false},
+		"temporary function":             {sql: "CREATE TEMP FUNCTION f() AS (1);\nSELECT f();", want: false},
+		"temporary functions":            {sql: "CREATE OR REPLACE TEMPORARY FUNCTION f() AS (1); CREATE TEMP FUNCTION g() AS (2); SELECT f(), g()", want: false},
+		"temporary function in a script": {sql: "CREATE TEMP FUNCTION f() AS (1); SELECT f(); SELECT f()", want: true},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isScript(tt.sql))
+		})
+	}
+}
+
+// scriptChildJob is a child job listed for the fake script job.
+type scriptChildJob struct {
+	id            string
+	creationTime  string
+	statementType string
+	kind          string
+	text          string
+	line          int
+	affectedRows  string
+}
+
+func TestBigQueryStatementQueryContextScript(t *testing.T) {
+	t.Parallel()
+
+	script := "DECLARE x INT64 DEFAULT 1;\nSELECT x AS n;\nIF x > 0 THEN INSERT INTO t VALUES (x); END IF;\nSELECT 2 AS n;"
+	children := []scriptChildJob{
+		// Listed from the most recent job.
+		{id: "select2", creationTime: "4000", statementType: "SELECT", kind: "STATEMENT", text: "SELECT 2 AS n", line: 4},
+		{id: "insert", creationTime: "3000", statementType: "INSERT", kind: "STATEMENT", text: "INSERT INTO t VALUES (x)", line: 3, affectedRows: "1"},
+		{id: "condition", creationTime: "2000", statementType: "SELECT", kind: "EXPRESSION", text: "x > 0", line: 3},
+		{id: "select1", creationTime: "1000", statementType: "SELECT", kind: "STATEMENT", text: "SELECT x AS n", line: 2},
+	}
+
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/project/queries":
+			writeQueryResponse(w, "2")
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/project/jobs":
+			assert.Equal(t, "job", r.URL.Query().Get("parentJobId"))
+			var jobs []map[string]any
+			for _, child := range children {
+				statistics := map[string]any{
+					"creationTime": child.creationTime,
+					"query":        map[string]any{"statementType": child.statementType},
+					"scriptStatistics": map[string]any{
+						"evaluationKind": child.kind,
+						"stackFrames":    []map[string]any{{"startLine": child.line, "startColumn": 1, "endLine": child.line, "endColumn": len(child.text), "text": child.text}},
+					},
+				}
+				if child.affectedRows != "" {
+					statistics["query"].(map[string]any)["numDmlAffectedRows"] = child.affectedRows
+				}
+				jobs = append(jobs, map[string]any{
+					"jobReference":  map[string]string{"projectId": "project", "jobId": child.id, "location": "US"},
+					"configuration": map[string]any{"query": map[string]any{"query": child.text}},
+					"statistics":    statistics,
+					"status":        map[string]any{"state": "DONE"},
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"jobs": jobs})
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/project/queries/select1":
+			writeQueryResponse(w, "1")
+		case r.Method == http.MethodGet && r.URL.Path == "/projects/project/queries/select2":
+			writeQueryResponse(w, "2", "3")
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+		}
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var statements []ScriptStatement
+	ctx := WithScriptStatements(context.Background(), func(s []ScriptStatement) { statements = s })
+	rows, err := db.QueryContext(ctx, script)
+	require.NoError(t, err)
+	t.Cleanup(func() { rows.Close() })
+
+	var resultSets [][]int64
+	for {
+		var values []int64
+		for rows.Next() {
+			var n int64
+			require.NoError(t, rows.Scan(&n))
+			values = append(values, n)
+		}
+		resultSets = append(resultSets, values)
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, [][]int64{{1}, {2, 3}}, resultSets)
+
+	require.Len(t, statements, 3)
+	assert.Equal(t, []string{"select1", "insert", "select2"}, []string{statements[0].JobID, statements[1].JobID, statements[2].JobID})
+	assert.Equal(t, []int{0, -1, 1}, []int{statements[0].ResultSet, statements[1].ResultSet, statements[2].ResultSet})
+	assert.Equal(t, ScriptStatement{
+		JobID:              "insert",
+		StatementType:      "INSERT",
+		Text:               "INSERT INTO t VALUES (x)",
+		StartLine:          3,
+		StartColumn:        1,
+		EndLine:            3,
+		EndColumn:          int64(len("INSERT INTO t VALUES (x)")),
+		NumDMLAffectedRows: 1,
+		ResultSet:          -1,
+	}, statements[1])
+}
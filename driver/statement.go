@@ -4,12 +4,17 @@ import (
 	"context"
 	"database/sql/driver"
 	"errors"
+	"fmt"
+	"time"
 
 	"cloud.google.com/go/bigquery"
 	"github.com/basemachina/go-bigquery/adaptor"
 	"github.com/sirupsen/logrus"
 )
 
+// jobCancelTimeout bounds the request that cancels a job after its context is done.
+const jobCancelTimeout = 10 * time.Second
+
 type bigQueryStatement struct {
 	connection *bigQueryConnection
 	query      string
@@ -19,12 +24,26 @@ func (statement bigQueryStatement) Close() error {
 	return nil
 }
 
+// NumInput returns the number of positional parameters, so that database/sql checks the argument count.
+// It returns -1 for queries with named parameters, which are checked when the query is built.
 func (statement bigQueryStatement) NumInput() int {
-	return -1
+	sql := statement.query
+	if statement.connection.config.TranslatePlaceholders {
+		translation, err := translatePlaceholders(sql)
+		if err != nil || translation.positions != nil || translation.names != nil {
+			return translation.numInput()
+		}
+	}
+
+	placeholders, err := parsePlaceholders(sql)
+	if err != nil || len(placeholders.named) > 0 {
+		return -1
+	}
+	return placeholders.positional
 }
 
-func (bigQueryStatement) CheckNamedValue(*driver.NamedValue) error {
-	return nil
+func (bigQueryStatement) CheckNamedValue(namedValue *driver.NamedValue) error {
+	return checkNamedValue(namedValue)
 }
 
 func (statement *bigQueryStatement) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
@@ -36,17 +55,23 @@ func (statement *bigQueryStatement) ExecContext(ctx context.Context, args []driv
 		}
 	}
 
-	query, err := statement.buildQuery(convertParameters(args))
+	query, outs, err := statement.buildCall(ctx, convertParameters(args))
 	if err != nil {
 		return nil, err
 	}
 
-	rowIterator, err := query.Read(ctx)
+	rowIterator, err := statement.run(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &bigQueryResult{rowIterator}, nil
+	if len(outs) > 0 {
+		if err := assignOutArguments(rowIterator, outs); err != nil {
+			return nil, err
+		}
+	}
+
+	return newResult(ctx, rowIterator)
 }
 
 func (statement *bigQueryStatement) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
@@ -82,20 +107,17 @@ func (statement *bigQueryStatement) QueryContext(ctx context.Context, args []dri
 		}, nil
 	}
 
-	query, err := statement.buildQuery(convertParameters(args))
+	query, err := statement.buildQuery(ctx, convertParameters(args))
 	if err != nil {
 		return nil, err
 	}
 
-	rowIterator, err := query.Read(context.Background())
+	rowIterator, err := statement.run(ctx, query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &bigQueryRows{
-		source: createSourceFromRowIterator(rowIterator, adaptor.GetSchemaAdaptor(ctx)),
-	}, nil
-
+	return statement.newRows(ctx, query, rowIterator, adaptor.GetSchemaAdaptor(ctx))
 }
 
 func (statement bigQueryStatement) Exec(args []driver.Value) (driver.Result, error) {
@@ -108,17 +130,17 @@ func (statement bigQueryStatement) Exec(args []driver.Value) (driver.Result, err
 		}
 	}
 
-	query, err := statement.buildQuery(args)
+	query, err := statement.buildQuery(context.Background(), args)
 	if err != nil {
 		return nil, err
 	}
 
-	rowIterator, err := query.Read(context.Background())
+	rowIterator, err := statement.run(context.Background(), query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &bigQueryResult{rowIterator}, nil
+	return newResult(context.Background(), rowIterator)
 }
 
 func (statement bigQueryStatement) Query(args []driver.Value) (driver.Rows, error) {
@@ -130,32 +152,151 @@ func (statement bigQueryStatement) Query(args []driver.Value) (driver.Rows, erro
 		}
 	}
 
-	query, err := statement.buildQuery(args)
+	query, err := statement.buildQuery(context.Background(), args)
 	if err != nil {
 		return nil, err
 	}
 
-	rowIterator, err := query.Read(context.Background())
+	rowIterator, err := statement.run(context.Background(), query)
 	if err != nil {
 		return nil, err
 	}
 
-	return &bigQueryRows{source: createSourceFromRowIterator(rowIterator, nil)}, nil
+	return statement.newRows(context.Background(), query, rowIterator, nil)
 }
 
-func (statement bigQueryStatement) buildQuery(args []driver.Value) (*bigquery.Query, error) {
+// newRows returns the rows of a query. The rows of a script are those of its SELECT statements,
+// one result set each, rather than only those of its last statement.
+func (statement bigQueryStatement) newRows(ctx context.Context, query *bigquery.Query, rowIterator *bigquery.RowIterator, schemaAdaptor adaptor.SchemaAdaptor) (*bigQueryRows, error) {
+	resultJobs, err := statement.scriptResultJobs(ctx, query, rowIterator)
+	if err != nil {
+		return nil, err
+	}
+	if len(resultJobs) == 0 {
+		return &bigQueryRows{source: createSourceFromRowIterator(rowIterator, schemaAdaptor)}, nil
+	}
 
-	query, err := statement.connection.query(statement.query)
+	rows := &bigQueryRows{script: &scriptResultSets{ctx: ctx, jobs: resultJobs, schemaAdaptor: schemaAdaptor}}
+	if rows.source, err = rows.script.nextSource(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// scriptResultJobs lists the child jobs of a script, reports its statements to the handler
+// given with WithScriptStatements, and returns the jobs of the statements that returned rows.
+// It returns nothing for queries that are not scripts.
+func (statement bigQueryStatement) scriptResultJobs(ctx context.Context, query *bigquery.Query, rowIterator *bigquery.RowIterator) ([]*bigquery.Job, error) {
+	job := rowIterator.SourceJob()
+	if job == nil || !isScript(query.Q) {
+		return nil, nil
+	}
+
+	statements, resultJobs, err := scriptChildJobs(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	if handler := getScriptStatementsHandler(ctx); handler != nil {
+		handler(statements)
+	}
+	return resultJobs, nil
+}
+
+var errOutArgumentsNotExec = errors.New("sql.Out arguments are only supported by Exec")
+
+func (statement bigQueryStatement) buildQuery(ctx context.Context, args []driver.Value) (*bigquery.Query, error) {
+	query, outs, err := statement.buildCall(ctx, args)
 	if err != nil {
 		return nil, err
 	}
-	query.DefaultDatasetID = statement.connection.config.dataSet
+	if len(outs) > 0 {
+		return nil, errOutArgumentsNotExec
+	}
+	return query, nil
+}
+
+// buildCall builds the query like buildQuery, and also accepts sql.Out arguments of a CALL
+// statement, whose values the query selects in the order of the returned arguments.
+func (statement bigQueryStatement) buildCall(ctx context.Context, args []driver.Value) (*bigquery.Query, []outArgument, error) {
+
+	sql := statement.query
+	if statement.connection.config.TranslatePlaceholders {
+		translation, err := translatePlaceholders(sql)
+		if err != nil {
+			return nil, nil, err
+		}
+		if args, err = translation.args(args); err != nil {
+			return nil, nil, err
+		}
+		sql = translation.sql
+	}
+
+	placeholders, err := parsePlaceholders(sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := placeholders.check(args); err != nil {
+		return nil, nil, err
+	}
+	sql, args, outs, err := callWithOutArguments(sql, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	sql = withTempFunctions(statement.connection.tempFunctions, sql)
+
+	query, err := statement.connection.query(ctx, sql)
+	if err != nil {
+		return nil, nil, err
+	}
+	query.DefaultProjectID, query.DefaultDatasetID = statement.connection.config.defaultDatasetOf(ctx)
+	switch statement.connection.config.jobCreationModeOf(ctx) {
+	case bigquery.JobCreationModeRequired:
+		requireJobCreation(query)
+	case bigquery.JobCreationModeOptional:
+		if !isSingleQueryStatement(sql) {
+			requireJobCreation(query)
+		}
+	}
+	applyQueryOptions(ctx, query)
+	if err := validateConnectionProperties(query.ConnectionProperties); err != nil {
+		return nil, nil, err
+	}
+	if len(query.ConnectionProperties) > 0 {
+		// Queries run without a job by jobs.query cannot carry connection properties.
+		requireJobCreation(query)
+	}
 	query.Parameters, err = statement.buildParameters(args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return query, outs, err
+}
+
+// run starts the query and waits for its rows.
+// When ctx can be cancelled, the job is inserted first and waited for separately, so that it
+// can be cancelled when ctx is done before it finishes; BigQuery would keep running it otherwise.
+// Queries that BigQuery may run without a job are read directly as there is no job to cancel.
+func (statement bigQueryStatement) run(ctx context.Context, query *bigquery.Query) (*bigquery.RowIterator, error) {
+	jobCreationOptional := statement.connection.config.jobCreationModeOf(ctx) == bigquery.JobCreationModeOptional && query.JobID == ""
+	if ctx.Done() == nil || jobCreationOptional {
+		return query.Read(ctx)
+	}
+
+	job, err := query.Run(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	return query, err
+	rowIterator, err := job.Read(ctx)
+	if err != nil && ctx.Err() != nil {
+		cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), jobCancelTimeout)
+		defer cancel()
+		if cancelErr := job.Cancel(cancelCtx); cancelErr != nil {
+			logrus.Warnf("failed to cancel job %s: %v", job.ID(), cancelErr)
+		}
+	}
+	return rowIterator, err
 }
 
 func (statement bigQueryStatement) buildParameters(args []driver.Value) ([]bigquery.QueryParameter, error) {
@@ -165,12 +306,16 @@ func (statement bigQueryStatement) buildParameters(args []driver.Value) ([]bigqu
 
 	var parameters []bigquery.QueryParameter
 	for _, arg := range args {
-		parameters = append(parameters, buildParameter(arg))
+		parameter, err := buildParameter(arg)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, parameter)
 	}
 	return parameters, nil
 }
 
-func buildParameter(arg driver.Value) bigquery.QueryParameter {
+func buildParameter(arg driver.Value) (bigquery.QueryParameter, error) {
 	name := ""
 	value := arg
 
@@ -180,22 +325,28 @@ func buildParameter(arg driver.Value) bigquery.QueryParameter {
 		value = namedValue.Value
 	}
 
-	// Converts nil *float64 values to bigquery.NullFloat64.
-	// This is required by [bigquery.QueryParameter] to represent NULL values.
-	// Currently, this implementation only handles NULL support for float64,
-	// as BaseMachina only requires NULL handling for float64 at this time.
-	f, ok := value.(*float64)
-	if ok && f == nil {
-		value = bigquery.NullFloat64{
-			Valid:   false,
-			Float64: 0,
-		}
+	// NULL values and empty arrays must be sent as typed values such as bigquery.NullFloat64,
+	// as [bigquery.QueryParameter] cannot infer their type.
+	value, err := convertParameterValue(value)
+	if err != nil {
+		return bigquery.QueryParameter{}, fmt.Errorf("invalid parameter %s: %w", parameterName(name, arg), err)
 	}
 
 	return bigquery.QueryParameter{
 		Name:  name,
 		Value: value,
+	}, nil
+}
+
+// parameterName names a parameter in error messages.
+func parameterName(name string, arg driver.Value) string {
+	if name != "" {
+		return "@" + name
+	}
+	if namedValue, ok := arg.(driver.NamedValue); ok {
+		return fmt.Sprintf("%d", namedValue.Ordinal)
 	}
+	return "?"
 }
 
 func convertParameters(args []driver.NamedValue) []driver.Value {
@@ -1,11 +1,25 @@
 package driver
 
 import (
+	"context"
+	"database/sql"
 	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"cloud.google.com/go/bigquery"
+	"cloud.google.com/go/civil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/option"
 )
 
 func Test_buildParameter(t *testing.T) {
@@ -20,7 +34,7 @@ func Test_buildParameter(t *testing.T) {
 	}{
 		"simple value": {
 			arg:  123,
-			want: bigquery.QueryParameter{Name: "", Value: 123},
+			want: bigquery.QueryParameter{Name: "", Value: int64(123)},
 		},
 		"string value": {
 			arg:  "hello",
@@ -40,11 +54,11 @@ func Test_buildParameter(t *testing.T) {
 		},
 		"non-nil float64 pointer": {
 			arg:  &floatVal,
-			want: bigquery.QueryParameter{Name: "", Value: &floatVal},
+			want: bigquery.QueryParameter{Name: "", Value: floatVal},
 		},
 		"named non-nil float64 pointer": {
 			arg:  driver.NamedValue{Name: "param", Value: &floatVal},
-			want: bigquery.QueryParameter{Name: "param", Value: &floatVal},
+			want: bigquery.QueryParameter{Name: "param", Value: floatVal},
 		},
 	}
 
@@ -52,10 +66,1081 @@ func Test_buildParameter(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			got := buildParameter(tt.arg)
+			got, err := buildParameter(tt.arg)
+			if err != nil {
+				t.Fatalf("buildParameter() error = %v", err)
+			}
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildParameter() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+type nullableStatus struct {
+	Status string
+	Valid  bool
+}
+
+func (s nullableStatus) Value() (driver.Value, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	return s.Status, nil
+}
+
+type failingValuer struct{}
+
+func (failingValuer) Value() (driver.Value, error) {
+	return nil, errors.New("broken value")
+}
+
+type opaqueValuer struct{}
+
+func (*opaqueValuer) Value() (driver.Value, error) {
+	return nil, nil
+}
+
+func Test_buildParameter_null(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		arg  driver.Value
+		want interface{}
+	}{
+		"nil string pointer":           {arg: (*string)(nil), want: bigquery.NullString{}},
+		"nil int64 pointer":            {arg: (*int64)(nil), want: bigquery.NullInt64{}},
+		"nil int pointer":              {arg: (*int)(nil), want: bigquery.NullInt64{}},
+		"nil uint32 pointer":           {arg: (*uint32)(nil), want: bigquery.NullInt64{}},
+		"nil float32 pointer":          {arg: (*float32)(nil), want: bigquery.NullFloat64{}},
+		"nil bool pointer":             {arg: (*bool)(nil), want: bigquery.NullBool{}},
+		"nil time pointer":             {arg: (*time.Time)(nil), want: bigquery.NullTimestamp{}},
+		"nil civil date pointer":       {arg: (*civil.Date)(nil), want: bigquery.NullDate{}},
+		"nil civil time pointer":       {arg: (*civil.Time)(nil), want: bigquery.NullTime{}},
+		"nil civil datetime pointer":   {arg: (*civil.DateTime)(nil), want: bigquery.NullDateTime{}},
+		"nil big.Rat pointer":          {arg: (*big.Rat)(nil), want: typedNullParameterValue(bigquery.NumericFieldType)},
+		"nil interval pointer":         {arg: (*bigquery.IntervalValue)(nil), want: typedNullParameterValue(bigquery.IntervalFieldType)},
+		"nil bytes":                    {arg: []byte(nil), want: typedNullParameterValue(bigquery.BytesFieldType)},
+		"nil bytes pointer":            {arg: (*[]byte)(nil), want: typedNullParameterValue(bigquery.BytesFieldType)},
+		"empty bytes":                  {arg: []byte{}, want: []byte{}},
+		"invalid sql.NullString":       {arg: sql.NullString{}, want: bigquery.NullString{}},
+		"invalid sql.NullInt64":        {arg: sql.NullInt64{}, want: bigquery.NullInt64{}},
+		"invalid sql.NullInt32":        {arg: sql.NullInt32{}, want: bigquery.NullInt64{}},
+		"invalid sql.NullInt16":        {arg: sql.NullInt16{}, want: bigquery.NullInt64{}},
+		"invalid sql.NullByte":         {arg: sql.NullByte{}, want: bigquery.NullInt64{}},
+		"invalid sql.NullFloat64":      {arg: sql.NullFloat64{}, want: bigquery.NullFloat64{}},
+		"invalid sql.NullBool":         {arg: sql.NullBool{}, want: bigquery.NullBool{}},
+		"invalid sql.NullTime":         {arg: sql.NullTime{}, want: bigquery.NullTimestamp{}},
+		"invalid sql.Null[string]":     {arg: sql.Null[string]{}, want: bigquery.NullString{}},
+		"invalid sql.Null[civil.Date]": {arg: sql.Null[civil.Date]{}, want: bigquery.NullDate{}},
+		"nil sql.NullString pointer":   {arg: (*sql.NullString)(nil), want: bigquery.NullString{}},
+		"valid sql.NullString":         {arg: sql.NullString{String: "a", Valid: true}, want: "a"},
+		"valid sql.NullInt32":          {arg: sql.NullInt32{Int32: 1, Valid: true}, want: int64(1)},
+		"valid sql.NullTime":           {arg: sql.NullTime{Time: now, Valid: true}, want: now},
+		"custom valuer returning nil":  {arg: nullableStatus{}, want: bigquery.NullString{}},
+		"custom valuer with value":     {arg: nullableStatus{Status: "active", Valid: true}, want: "active"},
+		"named nil string pointer":     {arg: driver.NamedValue{Name: "param", Value: (*string)(nil)}, want: bigquery.NullString{}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := buildParameter(tt.arg)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.Value)
+		})
+	}
+}
+
+func Test_buildParameter_nullError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		arg     driver.Value
+		wantErr string
+	}{
+		"failing valuer": {
+			arg:     driver.NamedValue{Ordinal: 1, Value: failingValuer{}},
+			wantErr: "invalid parameter 1: broken value",
+		},
+		"valuer of unknown type": {
+			arg:     driver.NamedValue{Name: "param", Value: &opaqueValuer{}},
+			wantErr: "invalid parameter @param: cannot determine the BigQuery type of a NULL driver.opaqueValuer parameter",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buildParameter(tt.arg)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+type status string
+
+func TestBigQueryStatementCheckNamedValue(t *testing.T) {
+	t.Parallel()
+
+	text := "text"
+	date := civil.Date{Year: 2024, Month: 1, Day: 2}
+	tests := map[string]struct {
+		value any
+		want  any
+	}{
+		"nil":               {value: nil, want: nil},
+		"int":               {value: 1, want: int64(1)},
+		"uint16":            {value: uint16(2), want: int64(2)},
+		"float32":           {value: float32(0.5), want: float64(0.5)},
+		"named string type": {value: status("active"), want: "active"},
+		"string pointer":    {value: &text, want: "text"},
+		"nil string":        {value: (*string)(nil), want: bigquery.NullString{}},
+		"bytes":             {value: []byte("abc"), want: []byte("abc")},
+		"time":              {value: time.Unix(0, 0), want: time.Unix(0, 0)},
+		"rat":               {value: big.NewRat(1, 4), want: typedParameterValue(bigquery.NumericFieldType, "0.250000000")},
+		"bignumeric rat":    {value: big.NewRat(1, 1e12), want: typedParameterValue(bigquery.BigNumericFieldType, "0.00000000000100000000000000000000000000")},
+		"large rat":         {value: new(big.Rat).SetFrac(new(big.Int).Exp(big.NewInt(10), big.NewInt(30), nil), big.NewInt(1)), want: typedParameterValue(bigquery.BigNumericFieldType, "1000000000000000000000000000000.00000000000000000000000000000000000000")},
+		"numeric":           {value: Numeric("1.5"), want: typedParameterValue(bigquery.NumericFieldType, "1.5")},
+		"bignumeric":        {value: BigNumeric("-12345678901234567890.123"), want: typedParameterValue(bigquery.BigNumericFieldType, "-12345678901234567890.123")},
+		"largest numeric":   {value: Numeric("-99999999999999999999999999999.999999999"), want: typedParameterValue(bigquery.NumericFieldType, "-99999999999999999999999999999.999999999")},
+		"json":              {value: JSON(`{"a":1}`), want: typedParameterValue(bigquery.JSONFieldType, `{"a":1}`)},
+		"geography":         {value: Geography("POINT(1 2)"), want: typedParameterValue(bigquery.GeographyFieldType, "POINT(1 2)")},
+		"date":              {value: date, want: typedParameterValue(bigquery.DateFieldType, "2024-01-02")},
+		"date pointer":      {value: &date, want: typedParameterValue(bigquery.DateFieldType, "2024-01-02")},
+		"datetime": {
+			value: civil.DateTime{Date: date, Time: civil.Time{Hour: 3, Minute: 4, Second: 5}},
+			want:  typedParameterValue(bigquery.DateTimeFieldType, "2024-01-02 03:04:05"),
+		},
+		"time of day": {
+			value: civil.Time{Hour: 3, Minute: 4, Second: 5, Nanosecond: 6000},
+			want:  typedParameterValue(bigquery.TimeFieldType, "03:04:05.000006"),
+		},
+		"valid sql.NullInt64": {value: sql.NullInt64{Int64: 7, Valid: true}, want: int64(7)},
+		"null sql.NullInt64":  {value: sql.NullInt64{}, want: bigquery.NullInt64{}},
+		"client null type":    {value: bigquery.NullString{StringVal: "a", Valid: true}, want: bigquery.NullString{StringVal: "a", Valid: true}},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			namedValue := driver.NamedValue{Ordinal: 1, Value: tt.value}
+			require.NoError(t, bigQueryStatement{}.CheckNamedValue(&namedValue))
+			assert.Equal(t, tt.want, namedValue.Value)
+		})
+	}
+}
+
+func TestBigQueryStatementCheckNamedValueError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value   driver.NamedValue
+		wantErr string
+	}{
+		"complex": {
+			value:   driver.NamedValue{Ordinal: 1, Value: complex(1, 2)},
+			wantErr: "invalid parameter 1: unsupported parameter type complex128",
+		},
+		"channel": {
+			value:   driver.NamedValue{Name: "param", Value: make(chan int)},
+			wantErr: "invalid parameter @param: unsupported parameter type chan int",
+		},
+		"map": {
+			value:   driver.NamedValue{Ordinal: 2, Value: map[string]any{"a": 1}},
+			wantErr: "invalid parameter 2: unsupported parameter type map[string]interface {}; use driver.Struct to send a map as a STRUCT",
+		},
+		"uint64 overflow": {
+			value:   driver.NamedValue{Ordinal: 1, Value: uint64(1) << 63},
+			wantErr: "invalid parameter 1: 9223372036854775808 overflows INT64",
+		},
+		"invalid numeric": {
+			value:   driver.NamedValue{Ordinal: 1, Value: Numeric("1.2.3")},
+			wantErr: `invalid parameter 1: invalid NUMERIC value "1.2.3"`,
+		},
+		"fraction numeric": {
+			value:   driver.NamedValue{Ordinal: 1, Value: Numeric("1/3")},
+			wantErr: `invalid parameter 1: invalid NUMERIC value "1/3"`,
+		},
+		"exponent numeric": {
+			value:   driver.NamedValue{Ordinal: 1, Value: Numeric("1e5")},
+			wantErr: `invalid parameter 1: invalid NUMERIC value "1e5"`,
+		},
+		"hexadecimal bignumeric": {
+			value:   driver.NamedValue{Ordinal: 1, Value: BigNumeric("0x10")},
+			wantErr: `invalid parameter 1: invalid BIGNUMERIC value "0x10"`,
+		},
+		"numeric with too many decimal places": {
+			value:   driver.NamedValue{Ordinal: 1, Value: Numeric("0.1234567891")},
+			wantErr: `invalid parameter 1: NUMERIC value "0.1234567891" has more than 29 digits before or 9 digits after the decimal point`,
+		},
+		"numeric with too many integer digits": {
+			value:   driver.NamedValue{Ordinal: 1, Value: Numeric("100000000000000000000000000000")},
+			wantErr: `invalid parameter 1: NUMERIC value "100000000000000000000000000000" has more than 29 digits before or 9 digits after the decimal point`,
+		},
+		"bignumeric with too many decimal places": {
+			value:   driver.NamedValue{Ordinal: 1, Value: BigNumeric("0.000000000000000000000000000000000000001")},
+			wantErr: `invalid parameter 1: BIGNUMERIC value "0.000000000000000000000000000000000000001" has more than 38 digits before or 38 digits after the decimal point`,
+		},
+		"rat with too many decimal places": {
+			value:   driver.NamedValue{Ordinal: 1, Value: big.NewRat(1, 3)},
+			wantErr: "invalid parameter 1: 1/3 cannot be sent as NUMERIC or BIGNUMERIC without rounding",
+		},
+		"failing valuer": {
+			value:   driver.NamedValue{Ordinal: 1, Value: failingValuer{}},
+			wantErr: "invalid parameter 1: broken value",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := bigQueryConnection{}.CheckNamedValue(&tt.value)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBigQueryStatementBuildQueryWithQueryOptions(t *testing.T) {
+	t.Parallel()
+
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	connection := &bigQueryConnection{
+		client: client,
+		config: Config{
+			DatasetID:      "dataset",
+			MaxBytesBilled: 1000,
+			Labels:         map[string]string{"team": "data", "user": "service"},
+		},
+	}
+	statement := bigQueryStatement{connection, "SELECT 1"}
+
+	ctx := WithQueryOptions(context.Background(), WithJobIDPrefix("report_"), WithLabels(map[string]string{"user": "alice"}))
+	ctx = WithQueryOptions(ctx, WithMaxBytesBilled(100), WithPriority(bigquery.BatchPriority))
+
+	query, err := statement.buildQuery(ctx, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "dataset", query.DefaultDatasetID)
+	assert.Equal(t, "report_", query.JobID)
+	assert.True(t, query.AddJobIDSuffix)
+	assert.Equal(t, map[string]string{"team": "data", "user": "alice"}, query.Labels)
+	assert.Equal(t, int64(100), query.MaxBytesBilled)
+	assert.Equal(t, bigquery.BatchPriority, query.Priority)
+	assert.Equal(t, map[string]string{"team": "data", "user": "service"}, connection.config.Labels)
+
+	query, err = statement.buildQuery(context.Background(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "", query.JobID)
+	assert.Equal(t, int64(1000), query.MaxBytesBilled)
+}
+
+func TestBigQueryStatementBuildQueryDefaultDataset(t *testing.T) {
+	t.Parallel()
+
+	client, err := bigquery.NewClient(context.Background(), "billing-project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	tests := map[string]struct {
+		config        Config
+		ctx           context.Context
+		wantProjectID string
+		wantDatasetID string
+	}{
+		"dataset in billing project": {
+			config:        Config{ProjectID: "billing-project", DatasetID: "dataset"},
+			wantProjectID: "billing-project",
+			wantDatasetID: "dataset",
+		},
+		"dataset in data project": {
+			config:        Config{ProjectID: "billing-project", DataProjectID: "data-project", DatasetID: "dataset"},
+			wantProjectID: "data-project",
+			wantDatasetID: "dataset",
+		},
+		"dataset from context": {
+			config:        Config{ProjectID: "billing-project", DataProjectID: "data-project", DatasetID: "dataset"},
+			ctx:           WithDefaultDataset(context.Background(), "tenant_a"),
+			wantProjectID: "data-project",
+			wantDatasetID: "tenant_a",
+		},
+		"project and dataset from context": {
+			config:        Config{ProjectID: "billing-project", DatasetID: "dataset"},
+			ctx:           WithDefaultDataset(context.Background(), "tenant-project.tenant_b"),
+			wantProjectID: "tenant-project",
+			wantDatasetID: "tenant_b",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			connection := &bigQueryConnection{client: client, config: tt.config}
+			query, err := bigQueryStatement{connection, "SELECT 1"}.buildQuery(ctx, nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantProjectID, query.DefaultProjectID)
+			assert.Equal(t, tt.wantDatasetID, query.DefaultDatasetID)
+
+			dataset := connection.getDataset(ctx)
+			assert.Equal(t, tt.wantProjectID, dataset.ProjectID)
+			assert.Equal(t, tt.wantDatasetID, dataset.DatasetID)
+		})
+	}
+}
+
+func TestBigQueryStatementBuildQueryConnectionProperties(t *testing.T) {
+	t.Parallel()
+
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	connection := &bigQueryConnection{
+		client: client,
+		config: Config{
+			DatasetID:            "dataset",
+			ConnectionProperties: map[string]string{"time_zone": "Asia/Tokyo", "query_label": "team:data"},
+		},
+	}
+	statement := bigQueryStatement{connection, "SELECT CURRENT_DATE()"}
+
+	tests := map[string]struct {
+		ctx     context.Context
+		want    []*bigquery.ConnectionProperty
+		wantErr string
+	}{
+		"connection defaults": {
+			ctx: context.Background(),
+			want: []*bigquery.ConnectionProperty{
+				{Key: "time_zone", Value: "Asia/Tokyo"},
+				{Key: "query_label", Value: "team:data"},
+			},
+		},
+		"override from context": {
+			ctx: WithQueryOptions(context.Background(), WithConnectionProperty("time_zone", "UTC"), WithConnectionProperty("dataset_project_id", "data-project")),
+			want: []*bigquery.ConnectionProperty{
+				{Key: "time_zone", Value: "UTC"},
+				{Key: "query_label", Value: "team:data"},
+				{Key: "dataset_project_id", Value: "data-project"},
+			},
+		},
+		"unsupported property": {
+			ctx:     WithQueryOptions(context.Background(), WithConnectionProperty("session_id", "abc")),
+			wantErr: "unsupported connection property 'session_id', supported properties are: time_zone, query_label, dataset_project_id",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := statement.buildQuery(tt.ctx, nil)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, query.ConnectionProperties)
+		})
+	}
+}
+
+func TestBigQueryStatementQueryContextSendsConnectionProperties(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		params string
+	}{
+		"default job creation mode": {
+			params: "&time_zone=Asia%2FTokyo",
+		},
+		"optional job creation mode": {
+			params: "&time_zone=Asia%2FTokyo&job_creation_mode=optional",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var mutex sync.Mutex
+			var properties []any
+			dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodPost && r.URL.Path == "/projects/project/jobs":
+					var job map[string]any
+					json.NewDecoder(r.Body).Decode(&job)
+					query := job["configuration"].(map[string]any)["query"].(map[string]any)
+					mutex.Lock()
+					properties = append(properties, query["connectionProperties"])
+					mutex.Unlock()
+					job["status"] = map[string]string{"state": "DONE"}
+					json.NewEncoder(w).Encode(job)
+				case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/projects/project/queries/"):
+					writeQueryResponse(w, "1")
+				default:
+					http.NotFound(w, r)
+				}
+			})
+
+			conn, err := bigQueryDriver{}.Open(dsn + tt.params)
+			require.NoError(t, err)
+			t.Cleanup(func() { conn.Close() })
+
+			statement := &bigQueryStatement{conn.(*bigQueryConnection), "SELECT CURRENT_DATETIME()"}
+			rows, err := statement.QueryContext(context.Background(), nil)
+			require.NoError(t, err)
+			require.NoError(t, rows.Close())
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			want := []any{map[string]any{"key": "time_zone", "value": "Asia/Tokyo"}}
+			assert.Equal(t, []any{want}, properties, "the query must be inserted as a job with the connection properties")
+		})
+	}
+}
+
+func TestIsSingleQueryStatement(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		sql  string
+		want bool
+	}{
+		"select":                     {sql: "SELECT 1", want: true},
+		"lower case select":          {sql: "select\n*\nfrom t", want: true},
+		"with":                       {sql: "WITH t AS (SELECT 1) SELECT * FROM t", want: true},
+		"parenthesized select":       {sql: "(SELECT 1) UNION ALL (SELECT 2)", want: true},
+		"leading comments":           {sql: "-- comment\n# comment\n/* block */ SELECT 1", want: true},
+		"trailing semicolon":         {sql: "SELECT 1;\n", want: true},
+		"insert":                     {sql: "INSERT INTO t (a) VALUES (1)", want: false},
+		"update":                     {sql: "UPDATE t SET a = 1 WHERE true", want: false},
+		"create table":               {sql: "CREATE TABLE t (a INT64)", want: false},
+		"script":                     {sql: "DECLARE x INT64 DEFAULT 1; SELECT x", want: false},
+		"multiple selects":           {sql: "SELECT 1; SELECT 2", want: false},
+		"select with keyword prefix": {sql: "SELECTED", want: false},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, isSingleQueryStatement(tt.sql))
+		})
+	}
+}
+
+func TestBigQueryStatementBuildQueryJobCreationMode(t *testing.T) {
+	t.Parallel()
+
+	client, err := bigquery.NewClient(context.Background(), "project", option.WithoutAuthentication())
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	optional := &bigQueryConnection{
+		client: client,
+		config: Config{DatasetID: "dataset", JobCreationMode: bigquery.JobCreationModeOptional},
+	}
+	unspecified := &bigQueryConnection{
+		client: client,
+		config: Config{DatasetID: "dataset"},
+	}
+
+	tests := map[string]struct {
+		connection *bigQueryConnection
+		sql        string
+		ctx        context.Context
+		wantJobID  string
+	}{
+		"select without job": {
+			connection: optional,
+			sql:        "SELECT 1",
+			ctx:        context.Background(),
+			wantJobID:  "",
+		},
+		"dml with job": {
+			connection: optional,
+			sql:        "DELETE FROM t WHERE true",
+			ctx:        context.Background(),
+			wantJobID:  requiredJobIDPrefix,
+		},
+		"script with job": {
+			connection: optional,
+			sql:        "DECLARE x INT64; SELECT x",
+			ctx:        context.Background(),
+			wantJobID:  requiredJobIDPrefix,
+		},
+		"select with job required by context": {
+			connection: optional,
+			sql:        "SELECT 1",
+			ctx:        WithQueryOptions(context.Background(), WithJobCreationRequired()),
+			wantJobID:  requiredJobIDPrefix,
+		},
+		"select with job required by job creation mode": {
+			connection: optional,
+			sql:        "SELECT 1",
+			ctx:        WithJobCreationMode(context.Background(), bigquery.JobCreationModeRequired),
+			wantJobID:  requiredJobIDPrefix,
+		},
+		"dml with job ID prefix": {
+			connection: optional,
+			sql:        "DELETE FROM t WHERE true",
+			ctx:        WithQueryOptions(context.Background(), WithJobIDPrefix("cleanup")),
+			wantJobID:  "cleanup",
+		},
+		"select on a connection without job creation mode": {
+			connection: unspecified,
+			sql:        "SELECT 1",
+			ctx:        context.Background(),
+			wantJobID:  "",
+		},
+		"dml with job creation optional by context": {
+			connection: unspecified,
+			sql:        "DELETE FROM t WHERE true",
+			ctx:        WithJobCreationMode(context.Background(), bigquery.JobCreationModeOptional),
+			wantJobID:  requiredJobIDPrefix,
+		},
+		"select with job creation optional by context": {
+			connection: unspecified,
+			sql:        "SELECT 1",
+			ctx:        WithJobCreationMode(context.Background(), bigquery.JobCreationModeOptional),
+			wantJobID:  "",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			query, err := bigQueryStatement{tt.connection, tt.sql}.buildQuery(tt.ctx, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantJobID, query.JobID)
+		})
+	}
+}
+
+// newFakeJobServer serves a query job that runs until complete is closed, recording the cancelled job IDs.
+func newFakeJobServer(t *testing.T, complete <-chan struct{}) (string, func() []string, func() int) {
+	t.Helper()
+
+	var mutex sync.Mutex
+	var cancelled []string
+	var inserted int
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/projects/project/jobs":
+			var job map[string]any
+			json.NewDecoder(r.Body).Decode(&job)
+			mutex.Lock()
+			inserted++
+			mutex.Unlock()
+			job["status"] = map[string]string{"state": "RUNNING"}
+			json.NewEncoder(w).Encode(job)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/cancel"):
+			mutex.Lock()
+			cancelled = append(cancelled, strings.Split(r.URL.Path, "/")[4])
+			mutex.Unlock()
+			json.NewEncoder(w).Encode(map[string]any{})
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/projects/project/queries/"):
+			select {
+			case <-complete:
+				writeQueryResponse(w, "1", "2")
+			default:
+				json.NewEncoder(w).Encode(map[string]any{"jobComplete": false})
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	cancelledJobs := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]string(nil), cancelled...)
+	}
+	insertedJobs := func() int {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return inserted
+	}
+	return dsn, cancelledJobs, insertedJobs
+}
+
+func TestBigQueryStatementQueryContextCancelsJob(t *testing.T) {
+	t.Parallel()
+
+	dsn, cancelled, _ := newFakeJobServer(t, make(chan struct{}))
+
+	conn, err := bigQueryDriver{}.Open(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	statement := &bigQueryStatement{conn.(*bigQueryConnection), "SELECT 1"}
+	_, err = statement.QueryContext(ctx, nil)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Len(t, cancelled(), 1)
+	assert.NotEmpty(t, cancelled()[0])
+}
+
+func TestBigQueryStatementExecContextWithCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	dsn, cancelled, inserted := newFakeJobServer(t, make(chan struct{}))
+
+	conn, err := bigQueryDriver{}.Open(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	statement := &bigQueryStatement{conn.(*bigQueryConnection), "DELETE FROM t WHERE true"}
+	_, err = statement.ExecContext(ctx, nil)
+	require.ErrorIs(t, err, context.Canceled)
+
+	assert.Zero(t, inserted())
+	assert.Empty(t, cancelled())
+}
+
+func TestBigQueryStatementQueryContextCompletedJob(t *testing.T) {
+	t.Parallel()
+
+	complete := make(chan struct{})
+	close(complete)
+	dsn, cancelled, inserted := newFakeJobServer(t, complete)
+
+	conn, err := bigQueryDriver{}.Open(dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	statement := &bigQueryStatement{conn.(*bigQueryConnection), "SELECT 1"}
+	rows, err := statement.QueryContext(ctx, nil)
+	require.NoError(t, err)
+
+	dest := make([]driver.Value, 1)
+	require.NoError(t, rows.Next(dest))
+	assert.Equal(t, int64(1), dest[0])
+
+	// Close stops the iteration even though the result has more rows to read.
+	require.NoError(t, rows.Close())
+	assert.Equal(t, io.EOF, rows.Next(dest))
+	assert.Equal(t, []string{"n"}, rows.Columns())
+
+	assert.Equal(t, 1, inserted())
+	assert.Empty(t, cancelled())
+}
+
+type arrayItem struct {
+	ID    int64  `bigquery:"id"`
+	Label string `bigquery:"label"`
+	Skip  string `bigquery:"-"`
+}
+
+func TestBigQueryStatementArrayParameters(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value     any
+		wantType  map[string]any
+		wantValue map[string]any
+	}{
+		"int slice": {
+			value:     []int{1, 2},
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "INT64"}},
+			wantValue: map[string]any{"arrayValues": []any{map[string]any{"value": "1"}, map[string]any{"value": "2"}}},
+		},
+		"empty string slice": {
+			value:     []string{},
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "STRING"}},
+			wantValue: map[string]any{},
+		},
+		"nil date slice": {
+			value:     []civil.Date(nil),
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "DATE"}},
+			wantValue: map[string]any{},
+		},
+		"numeric wrappers": {
+			value:     []Numeric{"1.5"},
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "NUMERIC"}},
+			wantValue: map[string]any{"arrayValues": []any{map[string]any{"value": "1.5"}}},
+		},
+		"interface slice": {
+			value:     []any{"a", "b"},
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "STRING"}},
+			wantValue: map[string]any{"arrayValues": []any{map[string]any{"value": "a"}, map[string]any{"value": "b"}}},
+		},
+		"struct slice": {
+			value: []arrayItem{{ID: 1, Label: "a", Skip: "x"}},
+			wantType: map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "STRUCT", "structTypes": []any{
+				map[string]any{"name": "id", "type": map[string]any{"type": "INT64"}},
+				map[string]any{"name": "label", "type": map[string]any{"type": "STRING"}},
+			}}},
+			wantValue: map[string]any{"arrayValues": []any{map[string]any{"structValues": map[string]any{
+				"id":    map[string]any{"value": "1"},
+				"label": map[string]any{"value": "a"},
+			}}}},
+		},
+		"empty struct pointer slice": {
+			value: []*arrayItem{},
+			wantType: map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "STRUCT", "structTypes": []any{
+				map[string]any{"name": "id", "type": map[string]any{"type": "INT64"}},
+				map[string]any{"name": "label", "type": map[string]any{"type": "STRING"}},
+			}}},
+			wantValue: map[string]any{},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gotType, gotValue := sendQueryParameter(t, tt.value)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantValue, gotValue)
+		})
+	}
+}
+
+func TestBigQueryStatementArrayParameterError(t *testing.T) {
+	t.Parallel()
+
+	text := "a"
+	tests := map[string]struct {
+		value   any
+		wantErr string
+	}{
+		"empty interface slice": {
+			value:   []any{},
+			wantErr: "invalid parameter 1: cannot determine the element type of an empty []interface {}",
+		},
+		"mixed interface slice": {
+			value:   []any{"a", 1},
+			wantErr: "invalid parameter 1: element 1 has type INT64, but the array has elements of type STRING",
+		},
+		"nested slice": {
+			value:   [][]int{{1}},
+			wantErr: "invalid parameter 1: arrays of arrays are not supported: []int",
+		},
+		"null element": {
+			value:   []*string{&text, nil},
+			wantErr: "invalid parameter 1: element 1 is NULL, but arrays cannot contain NULL",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buildParameter(driver.NamedValue{Ordinal: 1, Value: tt.value})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+type structAddress struct {
+	City string `bigquery:"city"`
+}
+
+type structKey struct {
+	Name      string         `bigquery:"name"`
+	Tags      []string       `bigquery:"tags"`
+	Address   structAddress  `bigquery:"address"`
+	Previous  *structAddress `bigquery:"previous"`
+	Nickname  *string        `bigquery:"nickname"`
+	unexposed string
+}
+
+type structTree struct {
+	Name     string
+	Children []structTree
+}
+
+type structLink struct {
+	Next *structLink
+}
+
+type structGroup struct {
+	Members []structMember
+}
+
+type structMember struct {
+	Group *structGroup
+}
+
+func TestBigQueryStatementStructParameters(t *testing.T) {
+	t.Parallel()
+
+	addressType := map[string]any{"type": "STRUCT", "structTypes": []any{
+		map[string]any{"name": "city", "type": map[string]any{"type": "STRING"}},
+	}}
+	keyType := map[string]any{"type": "STRUCT", "structTypes": []any{
+		map[string]any{"name": "name", "type": map[string]any{"type": "STRING"}},
+		map[string]any{"name": "tags", "type": map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "STRING"}}},
+		map[string]any{"name": "address", "type": addressType},
+		map[string]any{"name": "previous", "type": addressType},
+		map[string]any{"name": "nickname", "type": map[string]any{"type": "STRING"}},
+	}}
+	schema := bigquery.Schema{
+		{Name: "id", Type: bigquery.IntegerFieldType},
+		{Name: "scores", Type: bigquery.FloatFieldType, Repeated: true},
+		{Name: "owner", Type: bigquery.RecordFieldType, Schema: bigquery.Schema{{Name: "city", Type: bigquery.StringFieldType}}},
+	}
+	schemaType := map[string]any{"type": "STRUCT", "structTypes": []any{
+		map[string]any{"name": "id", "type": map[string]any{"type": "INT64"}},
+		map[string]any{"name": "scores", "type": map[string]any{"type": "ARRAY", "arrayType": map[string]any{"type": "FLOAT64"}}},
+		map[string]any{"name": "owner", "type": addressType},
+	}}
+
+	tests := map[string]struct {
+		value     any
+		wantType  map[string]any
+		wantValue map[string]any
+	}{
+		"go struct": {
+			value:    structKey{Name: "a", Tags: []string{"x"}, Address: structAddress{City: "Tokyo"}},
+			wantType: keyType,
+			wantValue: map[string]any{"structValues": map[string]any{
+				"name":     map[string]any{"value": "a"},
+				"tags":     map[string]any{"arrayValues": []any{map[string]any{"value": "x"}}},
+				"address":  map[string]any{"structValues": map[string]any{"city": map[string]any{"value": "Tokyo"}}},
+				"previous": map[string]any{"value": nil},
+				"nickname": map[string]any{"value": nil},
+			}},
+		},
+		"nil struct pointer": {
+			value:     (*structKey)(nil),
+			wantType:  keyType,
+			wantValue: map[string]any{"value": nil},
+		},
+		"map with schema": {
+			value: Struct{Schema: schema, Values: map[string]any{
+				"id":     1,
+				"scores": []float64{0.5},
+				"owner":  map[string]any{"city": "Osaka"},
+			}},
+			wantType: schemaType,
+			wantValue: map[string]any{"structValues": map[string]any{
+				"id":     map[string]any{"value": "1"},
+				"scores": map[string]any{"arrayValues": []any{map[string]any{"value": "0.5"}}},
+				"owner":  map[string]any{"structValues": map[string]any{"city": map[string]any{"value": "Osaka"}}},
+			}},
+		},
+		"map with missing fields": {
+			value:    Struct{Schema: schema, Values: map[string]any{"owner": structAddress{City: "Nagoya"}}},
+			wantType: schemaType,
+			wantValue: map[string]any{"structValues": map[string]any{
+				"id":     map[string]any{"value": nil},
+				"scores": map[string]any{},
+				"owner":  map[string]any{"structValues": map[string]any{"city": map[string]any{"value": "Nagoya"}}},
+			}},
+		},
+		"null map": {
+			value:     Struct{Schema: schema},
+			wantType:  schemaType,
+			wantValue: map[string]any{"value": nil},
+		},
+		"array of maps": {
+			value:    []Struct{{Schema: bigquery.Schema{{Name: "city", Type: bigquery.StringFieldType}}, Values: map[string]any{"city": "Kyoto"}}},
+			wantType: map[string]any{"type": "ARRAY", "arrayType": addressType},
+			wantValue: map[string]any{"arrayValues": []any{
+				map[string]any{"structValues": map[string]any{"city": map[string]any{"value": "Kyoto"}}},
+			}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gotType, gotValue := sendQueryParameter(t, tt.value)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantValue, gotValue)
+		})
+	}
+}
+
+func TestBigQueryStatementStructParameterError(t *testing.T) {
+	t.Parallel()
+
+	schema := bigquery.Schema{{Name: "id", Type: bigquery.IntegerFieldType}}
+	tests := map[string]struct {
+		value   any
+		wantErr string
+	}{
+		"unknown map field": {
+			value:   Struct{Schema: schema, Values: map[string]any{"id": 1, "name": "a", "age": 2}},
+			wantErr: "invalid parameter 1: fields not in the schema: age, name",
+		},
+		"array for a scalar field": {
+			value:   Struct{Schema: schema, Values: map[string]any{"id": []int{1}}},
+			wantErr: "invalid parameter 1: field id: cannot send ARRAY<INT64> as INT64",
+		},
+		"scalar for a repeated field": {
+			value:   Struct{Schema: bigquery.Schema{{Name: "ids", Type: bigquery.IntegerFieldType, Repeated: true}}, Values: map[string]any{"ids": 1}},
+			wantErr: "invalid parameter 1: field ids: expected a slice for a repeated field, got int",
+		},
+		"unsupported struct field": {
+			value:   struct{ C complex64 }{},
+			wantErr: "invalid parameter 1: field C: cannot determine the BigQuery type of a NULL complex64 parameter",
+		},
+		"struct containing itself": {
+			value:   structLink{},
+			wantErr: "invalid parameter 1: field Next: unsupported recursive type driver.structLink",
+		},
+		"struct containing itself through a slice": {
+			value:   structTree{Name: "root", Children: []structTree{{Name: "leaf"}}},
+			wantErr: "invalid parameter 1: field Children: unsupported recursive type driver.structTree",
+		},
+		"structs containing each other": {
+			value:   []structGroup{},
+			wantErr: "invalid parameter 1: field Members: field Group: unsupported recursive type driver.structGroup",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buildParameter(driver.NamedValue{Ordinal: 1, Value: tt.value})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestBigQueryStatementRangeAndIntervalParameters(t *testing.T) {
+	t.Parallel()
+
+	start := civil.Date{Year: 2024, Month: 1, Day: 1}
+	end := civil.Date{Year: 2024, Month: 2, Day: 1}
+	startTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	startDateTime := civil.DateTime{Date: start, Time: civil.Time{Hour: 9}}
+	dateRangeType := map[string]any{"type": "RANGE", "rangeElementType": map[string]any{"type": "DATE"}}
+	intervalType := map[string]any{"type": "INTERVAL"}
+
+	tests := map[string]struct {
+		value     any
+		wantType  map[string]any
+		wantValue map[string]any
+	}{
+		"interval": {
+			value:     &bigquery.IntervalValue{Years: 1, Days: 2, Hours: 3},
+			wantType:  intervalType,
+			wantValue: map[string]any{"value": "1-0 2 3:0:0"},
+		},
+		"duration": {
+			value:     90*time.Minute + 500*time.Millisecond,
+			wantType:  intervalType,
+			wantValue: map[string]any{"value": "0-0 0 1:30:0.5"},
+		},
+		"nil duration": {
+			value:     (*time.Duration)(nil),
+			wantType:  intervalType,
+			wantValue: map[string]any{"value": nil},
+		},
+		"date range": {
+			value:     DateRange{Start: &start, End: &end},
+			wantType:  dateRangeType,
+			wantValue: map[string]any{"rangeValue": map[string]any{"start": map[string]any{"value": "2024-01-01"}, "end": map[string]any{"value": "2024-02-01"}}},
+		},
+		"unbounded date range": {
+			value:     DateRange{End: &end},
+			wantType:  dateRangeType,
+			wantValue: map[string]any{"rangeValue": map[string]any{"end": map[string]any{"value": "2024-02-01"}}},
+		},
+		"fully unbounded date range": {
+			value:     DateRange{},
+			wantType:  dateRangeType,
+			wantValue: map[string]any{"rangeValue": map[string]any{}},
+		},
+		"nil date range": {
+			value:     (*DateRange)(nil),
+			wantType:  dateRangeType,
+			wantValue: map[string]any{"value": nil},
+		},
+		"datetime range": {
+			value:     DateTimeRange{Start: &startDateTime},
+			wantType:  map[string]any{"type": "RANGE", "rangeElementType": map[string]any{"type": "DATETIME"}},
+			wantValue: map[string]any{"rangeValue": map[string]any{"start": map[string]any{"value": "2024-01-01 09:00:00"}}},
+		},
+		"timestamp range": {
+			value:     TimestampRange{Start: &startTime},
+			wantType:  map[string]any{"type": "RANGE", "rangeElementType": map[string]any{"type": "TIMESTAMP"}},
+			wantValue: map[string]any{"rangeValue": map[string]any{"start": map[string]any{"value": "2024-01-01 00:00:00+00:00"}}},
+		},
+		"client range value": {
+			value:     &bigquery.RangeValue{Start: start},
+			wantType:  dateRangeType,
+			wantValue: map[string]any{"rangeValue": map[string]any{"start": map[string]any{"value": "2024-01-01"}}},
+		},
+		"array of ranges": {
+			value:     []DateRange{{Start: &start}},
+			wantType:  map[string]any{"type": "ARRAY", "arrayType": dateRangeType},
+			wantValue: map[string]any{"arrayValues": []any{map[string]any{"rangeValue": map[string]any{"start": map[string]any{"value": "2024-01-01"}}}}},
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			gotType, gotValue := sendQueryParameter(t, tt.value)
+			assert.Equal(t, tt.wantType, gotType)
+			assert.Equal(t, tt.wantValue, gotValue)
+		})
+	}
+}
+
+func TestBigQueryStatementRangeParameterError(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		value   any
+		wantErr string
+	}{
+		"unbounded client range value": {
+			value:   &bigquery.RangeValue{},
+			wantErr: "invalid parameter 1: cannot determine the element type of an unbounded RangeValue; use driver.DateRange, driver.DateTimeRange or driver.TimestampRange",
+		},
+		"mixed endpoints": {
+			value:   &bigquery.RangeValue{Start: civil.Date{Year: 2024, Month: 1, Day: 1}, End: time.Now()},
+			wantErr: "invalid parameter 1: range endpoints have different types DATE and TIMESTAMP",
+		},
+		"unsupported endpoint": {
+			value:   &bigquery.RangeValue{Start: 1},
+			wantErr: "invalid parameter 1: unsupported range endpoint type int",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := buildParameter(driver.NamedValue{Ordinal: 1, Value: tt.value})
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
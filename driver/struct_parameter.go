@@ -0,0 +1,283 @@
+package driver
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/bigquery"
+)
+
+// Struct is a STRUCT query parameter built from a map, whose fields are declared by Schema.
+// Fields missing from Values are NULL, and a nil Values is a NULL STRUCT.
+// RECORD fields take a map[string]interface{} or a Go struct, and repeated fields a slice.
+//
+//	db.Query("SELECT * FROM t WHERE (a, b) = (@key.a, @key.b)", sql.Named("key", driver.Struct{
+//		Schema: bigquery.Schema{{Name: "a", Type: bigquery.StringFieldType}, {Name: "b", Type: bigquery.IntegerFieldType}},
+//		Values: map[string]interface{}{"a": "x", "b": 1},
+//	}))
+type Struct struct {
+	Schema bigquery.Schema
+	Values map[string]interface{}
+}
+
+var typeOfStruct = reflect.TypeOf(Struct{})
+
+func (s Struct) parameterValue() (*bigquery.QueryParameterValue, error) {
+	value, err := recordParameterValue(s.Schema, s.Values)
+	if err != nil {
+		return nil, err
+	}
+	return &value, nil
+}
+
+// schemaParameterType returns the STRUCT type declared by schema.
+func schemaParameterType(schema bigquery.Schema) *bigquery.StandardSQLDataType {
+	structType := &bigquery.StandardSQLStructType{}
+	for _, field := range schema {
+		structType.Fields = append(structType.Fields, &bigquery.StandardSQLField{Name: field.Name, Type: fieldParameterType(field)})
+	}
+	return &bigquery.StandardSQLDataType{StructType: structType}
+}
+
+// fieldParameterType returns the type of a field of a schema, which is an ARRAY when the field is repeated.
+func fieldParameterType(field *bigquery.FieldSchema) *bigquery.StandardSQLDataType {
+	dataType := &bigquery.StandardSQLDataType{TypeKind: standardSQLTypeKind(field.Type)}
+	switch {
+	case field.Type == bigquery.RecordFieldType:
+		dataType = schemaParameterType(field.Schema)
+	case field.Type == bigquery.RangeFieldType && field.RangeElementType != nil:
+		rangeType := rangeParameterType(field.RangeElementType.Type)
+		dataType = &rangeType
+	}
+	if field.Repeated {
+		return &bigquery.StandardSQLDataType{ArrayElementType: dataType}
+	}
+	return dataType
+}
+
+// recordParameterValue converts a map or a Go struct to a STRUCT query parameter value with the fields of schema.
+func recordParameterValue(schema bigquery.Schema, value interface{}) (bigquery.QueryParameterValue, error) {
+	structType := schemaParameterType(schema)
+	values, err := recordValues(value)
+	if err != nil || values == nil {
+		return bigquery.QueryParameterValue{Type: *structType, Value: bigquery.NullString{}}, err
+	}
+
+	fields := make(map[string]bool, len(schema))
+	result := bigquery.QueryParameterValue{Type: *structType, StructValue: map[string]bigquery.QueryParameterValue{}}
+	for _, field := range schema {
+		fields[field.Name] = true
+		fieldValue, err := schemaFieldValue(field, values[field.Name])
+		if err != nil {
+			return bigquery.QueryParameterValue{}, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		result.StructValue[field.Name] = fieldValue
+	}
+
+	var unknown []string
+	for name := range values {
+		if !fields[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return bigquery.QueryParameterValue{}, fmt.Errorf("fields not in the schema: %s", strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
+
+// recordValues returns the field values of a map or a Go struct by field name, or nil when value is NULL.
+func recordValues(value interface{}) (map[string]interface{}, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+
+	switch {
+	case !rv.IsValid():
+		return nil, nil
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		values := make(map[string]interface{}, rv.Len())
+		for iter := rv.MapRange(); iter.Next(); {
+			values[iter.Key().String()] = iter.Value().Interface()
+		}
+		return values, nil
+	case isStructParameterType(rv.Type()):
+		values := make(map[string]interface{})
+		for _, field := range structFields(rv.Type()) {
+			values[field.name] = rv.FieldByIndex(field.index).Interface()
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("expected a map or a struct for a RECORD, got %T", value)
+}
+
+// schemaFieldValue converts the value of a field declared by a schema.
+func schemaFieldValue(field *bigquery.FieldSchema, value interface{}) (bigquery.QueryParameterValue, error) {
+	if field.Repeated {
+		element := *field
+		element.Repeated = false
+
+		result := bigquery.QueryParameterValue{Type: *fieldParameterType(field), Value: []interface{}{}}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		switch {
+		case !rv.IsValid() || rv.Kind() == reflect.Pointer:
+			return result, nil
+		case rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array:
+			return bigquery.QueryParameterValue{}, fmt.Errorf("expected a slice for a repeated field, got %T", value)
+		}
+		for i := 0; i < rv.Len(); i++ {
+			elementValue, err := schemaFieldValue(&element, rv.Index(i).Interface())
+			if err != nil {
+				return bigquery.QueryParameterValue{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			result.ArrayValue = append(result.ArrayValue, bigquery.QueryParameterValue{
+				Value:       elementValue.Value,
+				StructValue: elementValue.StructValue,
+			})
+		}
+		return result, nil
+	}
+
+	if field.Type == bigquery.RecordFieldType {
+		return recordParameterValue(field.Schema, value)
+	}
+
+	dataType := fieldParameterType(field)
+	converted, err := convertParameterValue(value)
+	if err != nil {
+		return bigquery.QueryParameterValue{}, err
+	}
+	if converted == nil {
+		return bigquery.QueryParameterValue{Type: *dataType, Value: bigquery.NullString{}}, nil
+	}
+	typed, err := typedParameterValueOf(converted)
+	if err != nil {
+		return bigquery.QueryParameterValue{}, err
+	}
+	if typed.Type.ArrayElementType != nil || typed.Type.StructType != nil {
+		return bigquery.QueryParameterValue{}, fmt.Errorf("cannot send %s as %s", typeName(typed.Type), dataType.TypeKind)
+	}
+	return bigquery.QueryParameterValue{Type: *dataType, Value: typed.Value}, nil
+}
+
+// nonStructTypes are the Go struct types that are not sent as STRUCT query parameters.
+var nonStructTypes = map[reflect.Type]bool{
+	typeOfGoTime:                             true,
+	typeOfDate:                               true,
+	typeOfTime:                               true,
+	typeOfDateTime:                           true,
+	typeOfRat:                                true,
+	typeOfIntervalValue:                      true,
+	typeOfStruct:                             true,
+	typeOfDateRange:                          true,
+	typeOfDateTimeRange:                      true,
+	typeOfTimestampRange:                     true,
+	reflect.TypeOf(bigquery.NullString{}):    true,
+	reflect.TypeOf(bigquery.NullInt64{}):     true,
+	reflect.TypeOf(bigquery.NullFloat64{}):   true,
+	reflect.TypeOf(bigquery.NullBool{}):      true,
+	reflect.TypeOf(bigquery.NullTimestamp{}): true,
+	reflect.TypeOf(bigquery.NullDate{}):      true,
+	reflect.TypeOf(bigquery.NullTime{}):      true,
+	reflect.TypeOf(bigquery.NullDateTime{}):  true,
+	reflect.TypeOf(bigquery.NullGeography{}): true,
+	reflect.TypeOf(bigquery.NullJSON{}):      true,
+	reflect.TypeOf(bigQueryReroutedColumn{}): true,
+}
+
+// isStructParameterType reports whether values of the Go struct type t are sent as STRUCT query parameters.
+func isStructParameterType(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct || nonStructTypes[t] {
+		return false
+	}
+	return !t.Implements(typeOfValuer) && !reflect.PointerTo(t).Implements(typeOfValuer)
+}
+
+// structField is a field of a Go struct sent as a field of a STRUCT query parameter.
+type structField struct {
+	name  string
+	index []int
+}
+
+// structFields returns the exported fields of t, named by their bigquery tag like the client library does.
+// Fields tagged with "-" are skipped, and the fields of untagged embedded structs are promoted.
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("bigquery"), ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			for _, promoted := range structFields(field.Type) {
+				fields = append(fields, structField{promoted.name, append([]int{i}, promoted.index...)})
+			}
+			continue
+		}
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, structField{name, []int{i}})
+	}
+	return fields
+}
+
+// structParameterType returns the STRUCT type of a Go struct. visiting holds the struct types
+// whose fields are being typed, as a STRUCT cannot contain itself, even through an ARRAY.
+func structParameterType(t reflect.Type, visiting map[reflect.Type]bool) (*bigquery.StandardSQLDataType, error) {
+	if visiting[t] {
+		return nil, fmt.Errorf("unsupported recursive type %s", t)
+	}
+	if visiting == nil {
+		visiting = make(map[reflect.Type]bool)
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	structType := &bigquery.StandardSQLStructType{}
+	for _, field := range structFields(t) {
+		dataType, err := parameterTypeOf(t.FieldByIndex(field.index).Type, visiting)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+		structType.Fields = append(structType.Fields, &bigquery.StandardSQLField{Name: field.name, Type: dataType})
+	}
+	return &bigquery.StandardSQLDataType{StructType: structType}, nil
+}
+
+// structParameterValue converts a Go struct to a STRUCT query parameter value.
+// Nil pointers to structs are sent as NULL fields of their STRUCT type.
+func structParameterValue(rv reflect.Value) (*bigquery.QueryParameterValue, error) {
+	structType, err := structParameterType(rv.Type(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	value := &bigquery.QueryParameterValue{Type: *structType, StructValue: map[string]bigquery.QueryParameterValue{}}
+	for _, field := range structFields(rv.Type()) {
+		converted, err := convertParameterValue(rv.FieldByIndex(field.index).Interface())
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+		typed, err := typedParameterValueOf(converted)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", field.name, err)
+		}
+		value.StructValue[field.name] = typed
+	}
+	return value, nil
+}
@@ -0,0 +1,264 @@
+package driver
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// tempFunction is a CREATE TEMP FUNCTION statement added to the queries that call the function.
+type tempFunction struct {
+	name       string
+	definition string
+}
+
+var (
+	tempFunctionsMutex sync.RWMutex
+	tempFunctionSets   = make(map[string][]tempFunction)
+)
+
+// RegisterTempFunctions makes temporary functions available by the provided name, each given by
+// its CREATE TEMP FUNCTION statement. A DSN refers to them with the "temp_functions" parameter,
+// for example "bigquery://project/dataset?temp_functions=masking,fiscal_calendar".
+// Queries are run with the definitions of the functions they call, and of the functions those call.
+// If RegisterTempFunctions is called twice with the same name or if a definition is invalid, it panics.
+func RegisterTempFunctions(name string, definitions ...string) {
+	functions := mustParseTempFunctions("RegisterTempFunctions", definitions)
+
+	tempFunctionsMutex.Lock()
+	defer tempFunctionsMutex.Unlock()
+
+	if _, dup := tempFunctionSets[name]; dup {
+		panic("bigquery: RegisterTempFunctions called twice for " + name)
+	}
+	tempFunctionSets[name] = functions
+}
+
+// DeregisterTempFunctions removes the temporary functions registered with the given name.
+func DeregisterTempFunctions(name string) {
+	tempFunctionsMutex.Lock()
+	defer tempFunctionsMutex.Unlock()
+
+	delete(tempFunctionSets, name)
+}
+
+// resolveTempFunctions returns the functions registered with the given names followed by functions,
+// rejecting functions defined twice.
+func resolveTempFunctions(names []string, functions []tempFunction) ([]tempFunction, error) {
+	var resolved []tempFunction
+	tempFunctionsMutex.RLock()
+	for _, name := range names {
+		set, ok := tempFunctionSets[name]
+		if !ok {
+			tempFunctionsMutex.RUnlock()
+			return nil, fmt.Errorf("unknown temporary functions '%s'", name)
+		}
+		resolved = append(resolved, set...)
+	}
+	tempFunctionsMutex.RUnlock()
+	resolved = append(resolved, functions...)
+
+	seen := make(map[string]bool, len(resolved))
+	for _, function := range resolved {
+		if seen[function.name] {
+			return nil, fmt.Errorf("temporary function %s is defined twice", function.name)
+		}
+		seen[function.name] = true
+	}
+	return resolved, nil
+}
+
+func mustParseTempFunctions(caller string, definitions []string) []tempFunction {
+	functions, err := parseTempFunctions(definitions)
+	if err != nil {
+		panic("bigquery: " + caller + ": " + err.Error())
+	}
+	return functions
+}
+
+func parseTempFunctions(definitions []string) ([]tempFunction, error) {
+	functions := make([]tempFunction, 0, len(definitions))
+	for _, definition := range definitions {
+		function, err := parseTempFunction(definition)
+		if err != nil {
+			return nil, err
+		}
+		functions = append(functions, function)
+	}
+	return functions, nil
+}
+
+// parseTempFunction reads the name of the function defined by a CREATE TEMP FUNCTION statement.
+func parseTempFunction(definition string) (tempFunction, error) {
+	tokens := significantTokens(tokenizeSQL(definition))
+	words := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		words = append(words, strings.ToUpper(token.text))
+	}
+
+	i := 0
+	match := func(keywords ...string) bool {
+		for j, keyword := range keywords {
+			if i+j >= len(words) || words[i+j] != keyword {
+				return false
+			}
+		}
+		i += len(keywords)
+		return true
+	}
+	if !match("CREATE") {
+		return tempFunction{}, fmt.Errorf("not a CREATE TEMP FUNCTION statement: %s", definition)
+	}
+	match("OR", "REPLACE")
+	if !match("TEMP", "FUNCTION") && !match("TEMPORARY", "FUNCTION") {
+		return tempFunction{}, fmt.Errorf("not a CREATE TEMP FUNCTION statement: %s", definition)
+	}
+	match("IF", "NOT", "EXISTS")
+	if i >= len(tokens) || tokens[i].kind != tokenWord && tokens[i].kind != tokenQuotedIdentifier {
+		return tempFunction{}, fmt.Errorf("missing function name: %s", definition)
+	}
+	name := functionName(tokens[i])
+
+	for j, token := range tokens {
+		switch {
+		case token.kind == tokenPositional, token.kind == tokenNamed:
+			return tempFunction{}, fmt.Errorf("temporary function %s cannot use query parameters", name)
+		case token.kind == tokenSymbol && token.text == ";" && j < len(tokens)-1:
+			return tempFunction{}, fmt.Errorf("temporary function %s must be defined by a single statement", name)
+		}
+	}
+
+	definition = strings.TrimSpace(definition)
+	if all := tokenizeSQL(definition); all[len(all)-1].kind == tokenComment {
+		// A line comment runs to the end of the line.
+		definition += "\n"
+	}
+	if tokens[len(tokens)-1].text != ";" {
+		definition += ";"
+	}
+	return tempFunction{name: name, definition: definition}, nil
+}
+
+// functionName returns the name of a function, which is case-insensitive, without backticks.
+func functionName(token sqlToken) string {
+	return strings.ToLower(strings.Trim(token.text, "`"))
+}
+
+// tableKeywords are the keywords followed by a table name, which may be followed by a list
+// of columns, as in INSERT INTO t (a) VALUES (1), rather than by the arguments of a call.
+var tableKeywords = map[string]bool{"INSERT": true, "INTO": true, "TABLE": true, "JOIN": true, "FROM": true, "UPDATE": true, "MERGE": true}
+
+// calledFunctions returns the names of the unqualified functions called by sql,
+// and those of the temporary functions it defines itself.
+func calledFunctions(sql string) (called map[string]bool, defined map[string]bool) {
+	called, defined = make(map[string]bool), make(map[string]bool)
+	tokens := significantTokens(tokenizeSQL(sql))
+	// callers holds the word before each open parenthesis.
+	var callers []string
+	for i := 0; i+1 < len(tokens); i++ {
+		token := tokens[i]
+		switch {
+		case token.kind == tokenSymbol && token.text == "(":
+			caller := ""
+			if i > 0 {
+				caller = strings.ToUpper(tokens[i-1].text)
+			}
+			callers = append(callers, caller)
+		case token.kind == tokenSymbol && token.text == ")" && len(callers) > 0:
+			callers = callers[:len(callers)-1]
+		}
+		if token.kind != tokenWord && token.kind != tokenQuotedIdentifier || tokens[i+1].text != "(" {
+			continue
+		}
+		if i > 0 && tokens[i-1].text == "." {
+			continue
+		}
+		if i > 0 && isTableKeyword(tokens[i-1], callers) {
+			continue
+		}
+		if i > 0 && strings.EqualFold(tokens[i-1].text, "FUNCTION") ||
+			i > 3 && strings.EqualFold(tokens[i-1].text, "EXISTS") && strings.EqualFold(tokens[i-4].text, "FUNCTION") {
+			defined[functionName(token)] = true
+			continue
+		}
+		called[functionName(token)] = true
+	}
+	return called, defined
+}
+
+// isTableKeyword reports whether token is followed by a table name. FROM is not in
+// EXTRACT(part FROM expression), which callers, the words before the open parentheses, tell.
+func isTableKeyword(token sqlToken, callers []string) bool {
+	if token.kind != tokenWord || !tableKeywords[strings.ToUpper(token.text)] {
+		return false
+	}
+	return !strings.EqualFold(token.text, "FROM") || len(callers) == 0 || callers[len(callers)-1] != "EXTRACT"
+}
+
+// withTempFunctions adds to sql the definitions of the functions it calls, directly or through
+// other functions, in the order the functions were registered. Functions that sql defines itself
+// are left out. The definitions follow the DECLARE statements that start a script, as BigQuery
+// allows DECLARE only there, so those statements cannot call the functions.
+func withTempFunctions(functions []tempFunction, sql string) string {
+	if len(functions) == 0 {
+		return sql
+	}
+
+	called, defined := calledFunctions(sql)
+	selected := make(map[string]bool)
+	for changed := true; changed; {
+		changed = false
+		for _, function := range functions {
+			if !called[function.name] || selected[function.name] || defined[function.name] {
+				continue
+			}
+			selected[function.name] = true
+			changed = true
+			calls, _ := calledFunctions(function.definition)
+			for name := range calls {
+				called[name] = true
+			}
+		}
+	}
+	if len(selected) == 0 {
+		return sql
+	}
+
+	offset := declarationsEnd(sql)
+	var builder strings.Builder
+	if offset > 0 {
+		builder.WriteString(sql[:offset] + "\n")
+	}
+	for _, function := range functions {
+		if selected[function.name] {
+			builder.WriteString(function.definition + "\n")
+		}
+	}
+	builder.WriteString(strings.TrimLeft(sql[offset:], " \t\r\n"))
+	return builder.String()
+}
+
+// declarationsEnd returns the offset in sql right after the DECLARE statements it starts with, or 0.
+func declarationsEnd(sql string) int {
+	end, offset := 0, 0
+	inDeclaration, statementStart := false, true
+	for _, token := range tokenizeSQL(sql) {
+		offset += len(token.text)
+		if token.kind == tokenSpace || token.kind == tokenComment {
+			continue
+		}
+		switch {
+		case statementStart:
+			if token.kind != tokenWord || !strings.EqualFold(token.text, "DECLARE") {
+				return end
+			}
+			inDeclaration, statementStart = true, false
+		case token.kind == tokenSymbol && token.text == ";":
+			end, inDeclaration, statementStart = offset, false, true
+		}
+	}
+	if inDeclaration {
+		return offset
+	}
+	return end
+}
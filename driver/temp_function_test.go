@@ -0,0 +1,219 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTempFunction(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		definition string
+		want       tempFunction
+		wantErr    string
+	}{
+		"sql function": {
+			definition: "CREATE TEMP FUNCTION Mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'))",
+			want:       tempFunction{name: "mask", definition: "CREATE TEMP FUNCTION Mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'));"},
+		},
+		"javascript function": {
+			definition: "\nCREATE OR REPLACE TEMPORARY FUNCTION IF NOT EXISTS `parse`(s STRING) RETURNS INT64 LANGUAGE js AS \"\"\"\n  return parseInt(s); // ?\n\"\"\";\n",
+			want:       tempFunction{name: "parse", definition: "CREATE OR REPLACE TEMPORARY FUNCTION IF NOT EXISTS `parse`(s STRING) RETURNS INT64 LANGUAGE js AS \"\"\"\n  return parseInt(s); // ?\n\"\"\";"},
+		},
+		"trailing comment": {
+			definition: "CREATE TEMP FUNCTION one() AS (1) -- one",
+			want:       tempFunction{name: "one", definition: "CREATE TEMP FUNCTION one() AS (1) -- one\n;"},
+		},
+		"not a function": {
+			definition: "CREATE TABLE t (a INT64)",
+			wantErr:    "not a CREATE TEMP FUNCTION statement: CREATE TABLE t (a INT64)",
+		},
+		"persistent function": {
+			definition: "CREATE FUNCTION dataset.one() AS (1)",
+			wantErr:    "not a CREATE TEMP FUNCTION statement: CREATE FUNCTION dataset.one() AS (1)",
+		},
+		"query parameter": {
+			definition: "CREATE TEMP FUNCTION f(x INT64) AS (x + @offset)",
+			wantErr:    "temporary function f cannot use query parameters",
+		},
+		"several statements": {
+			definition: "CREATE TEMP FUNCTION f() AS (1); SELECT f()",
+			wantErr:    "temporary function f must be defined by a single statement",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseTempFunction(tt.definition)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithTempFunctions(t *testing.T) {
+	t.Parallel()
+
+	functions := mustParseTempFunctions("test", []string{
+		"CREATE TEMP FUNCTION mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'))",
+		"CREATE TEMP FUNCTION fiscal_year(d DATE) AS (EXTRACT(YEAR FROM DATE_ADD(d, INTERVAL 9 MONTH)))",
+		"CREATE TEMP FUNCTION fiscal_quarter(d DATE) AS (CONCAT('FY', fiscal_year(d)))",
+		"CREATE TEMP FUNCTION fiscal_start(d DATE) AS (DATE_SUB(d, INTERVAL 3 MONTH))",
+	})
+	mask := "CREATE TEMP FUNCTION mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'));\n"
+	fiscalYear := "CREATE TEMP FUNCTION fiscal_year(d DATE) AS (EXTRACT(YEAR FROM DATE_ADD(d, INTERVAL 9 MONTH)));\n"
+	fiscalQuarter := "CREATE TEMP FUNCTION fiscal_quarter(d DATE) AS (CONCAT('FY', fiscal_year(d)));\n"
+	fiscalStart := "CREATE TEMP FUNCTION fiscal_start(d DATE) AS (DATE_SUB(d, INTERVAL 3 MONTH));\n"
+
+	tests := map[string]struct {
+		sql  string
+		want string
+	}{
+		"no calls": {
+			sql:  "SELECT mask FROM t",
+			want: "SELECT mask FROM t",
+		},
+		"call": {
+			sql:  "SELECT MASK(name) FROM t",
+			want: mask + "SELECT MASK(name) FROM t",
+		},
+		"calls through functions in registration order": {
+			sql:  "SELECT fiscal_quarter(d), mask(name) FROM t",
+			want: mask + fiscalYear + fiscalQuarter + "SELECT fiscal_quarter(d), mask(name) FROM t",
+		},
+		"qualified function": {
+			sql:  "SELECT dataset.mask(name) FROM t",
+			want: "SELECT dataset.mask(name) FROM t",
+		},
+		"literal and comment": {
+			sql:  "SELECT 'mask(name)' -- mask(name)\nFROM t",
+			want: "SELECT 'mask(name)' -- mask(name)\nFROM t",
+		},
+		"tables named like functions": {
+			sql:  "INSERT INTO mask (name) VALUES ('a');\nINSERT fiscal_year (d) VALUES (CURRENT_DATE());\nCREATE TABLE fiscal_start (d DATE)",
+			want: "INSERT INTO mask (name) VALUES ('a');\nINSERT fiscal_year (d) VALUES (CURRENT_DATE());\nCREATE TABLE fiscal_start (d DATE)",
+		},
+		"call in extract": {
+			sql:  "SELECT EXTRACT(YEAR FROM fiscal_start(d)) FROM t",
+			want: fiscalStart + "SELECT EXTRACT(YEAR FROM fiscal_start(d)) FROM t",
+		},
+		"script starting with declarations": {
+			sql:  "-- masked names\nDECLARE name STRING DEFAULT 'a';\nDECLARE n INT64;\nSELECT mask(name);",
+			want: "-- masked names\nDECLARE name STRING DEFAULT 'a';\nDECLARE n INT64;\n" + mask + "SELECT mask(name);",
+		},
+		"declarations only": {
+			sql:  "DECLARE name STRING DEFAULT mask('a')",
+			want: "DECLARE name STRING DEFAULT mask('a')\n" + mask,
+		},
+		"defined by the query": {
+			sql:  "CREATE TEMP FUNCTION mask(s STRING) AS ('*');\nSELECT mask(name) FROM t",
+			want: "CREATE TEMP FUNCTION mask(s STRING) AS ('*');\nSELECT mask(name) FROM t",
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, withTempFunctions(functions, tt.sql))
+		})
+	}
+}
+
+func TestRegisterTempFunctions(t *testing.T) {
+	var queries []string
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query string `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		queries = append(queries, request.Query)
+		writeQueryResponse(w, "1")
+	})
+
+	RegisterTempFunctions("test-functions", "CREATE TEMP FUNCTION one() AS (1)")
+	t.Cleanup(func() { DeregisterTempFunctions("test-functions") })
+
+	assert.Panics(t, func() { RegisterTempFunctions("test-functions", "CREATE TEMP FUNCTION two() AS (2)") })
+	assert.Panics(t, func() { RegisterTempFunctions("invalid-functions", "SELECT 1") })
+
+	db, err := sql.Open("bigquery", dsn+"&temp_functions=test-functions")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	require.NoError(t, db.QueryRow("SELECT one() AS n").Scan(&n))
+	require.NoError(t, db.QueryRow("SELECT 2 AS n").Scan(&n))
+	assert.Equal(t, []string{"CREATE TEMP FUNCTION one() AS (1);\nSELECT one() AS n", "SELECT 2 AS n"}, queries)
+
+	_, err = sql.Open("bigquery", dsn+"&temp_functions=unknown-functions")
+	assert.EqualError(t, err, "unknown temporary functions 'unknown-functions'")
+}
+
+func TestConnectorWithTempFunctions(t *testing.T) {
+	t.Parallel()
+
+	var query string
+	endpoint := newFakeBigQueryEndpoint(t, func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Query string `json:"query"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		query = request.Query
+		writeQueryResponse(w, "1")
+	})
+	config, err := ParseDSN("bigquery://project/dataset?disable_auth=true&endpoint=" + endpoint)
+	require.NoError(t, err)
+	client, err := newClient(context.Background(), config)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+
+	connector := NewConnector(client, WithConfig(config), WithTempFunctions(
+		"CREATE TEMP FUNCTION one() AS (1)",
+		"CREATE TEMP FUNCTION One() AS (1)",
+	))
+	_, err = connector.Connect(context.Background())
+	assert.EqualError(t, err, "temporary function one is defined twice")
+
+	connector = NewConnector(client, WithConfig(config), WithTempFunctions("SELECT 1"))
+	_, err = connector.Connect(context.Background())
+	assert.EqualError(t, err, "not a CREATE TEMP FUNCTION statement: SELECT 1")
+
+	connector = NewConnector(client, WithConfig(config), WithTempFunctions("CREATE TEMP FUNCTION one() AS (1)"))
+	db := sql.OpenDB(connector)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	require.NoError(t, db.QueryRow("SELECT one() AS n").Scan(&n))
+	assert.Equal(t, "CREATE TEMP FUNCTION one() AS (1);\nSELECT one() AS n", query)
+}
+
+func TestWithTempFunctionsOutArguments(t *testing.T) {
+	t.Parallel()
+
+	functions := mustParseTempFunctions("test", []string{"CREATE TEMP FUNCTION mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'))"})
+
+	var name string
+	script, _, _, err := callWithOutArguments("CALL dataset.procedure(mask(?), ?)", []driver.Value{
+		driver.NamedValue{Ordinal: 1, Value: "a"},
+		driver.NamedValue{Ordinal: 2, Value: sql.Out{Dest: &name}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "DECLARE _out_2 STRING;\n"+
+		"CREATE TEMP FUNCTION mask(s STRING) AS (REGEXP_REPLACE(s, r'.', '*'));\n"+
+		"CALL dataset.procedure(mask(?), _out_2);\nSELECT _out_2;", withTempFunctions(functions, script))
+}
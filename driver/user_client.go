@@ -0,0 +1,145 @@
+package driver
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/option"
+)
+
+// DefaultUserClientCacheSize is the number of end-user clients a connection pool keeps
+// when user_client_cache_size is not set.
+const DefaultUserClientCacheSize = 64
+
+var userCredentialsCtxKey = struct{ value string }{"userCredentialsCtxKey"}
+
+type userCredentials struct {
+	identity    string
+	tokenSource oauth2.TokenSource
+}
+
+// WithUserTokenSource returns a context that runs queries as the end user identified by identity,
+// authorized by tokenSource, instead of the credentials of the connection.
+// Clients are cached per identity, so identity must uniquely name the end user.
+func WithUserTokenSource(ctx context.Context, identity string, tokenSource oauth2.TokenSource) context.Context {
+	return context.WithValue(ctx, userCredentialsCtxKey, &userCredentials{identity, tokenSource})
+}
+
+// WithUserAccessToken returns a context that runs queries with the given end-user OAuth access token.
+func WithUserAccessToken(ctx context.Context, accessToken string) context.Context {
+	sum := sha256.Sum256([]byte(accessToken))
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: accessToken, TokenType: "Bearer"})
+	return WithUserTokenSource(ctx, "token:"+hex.EncodeToString(sum[:]), tokenSource)
+}
+
+func getUserCredentials(ctx context.Context) *userCredentials {
+	if ctx == nil {
+		return nil
+	}
+
+	value := ctx.Value(userCredentialsCtxKey)
+	if value == nil {
+		return nil
+	}
+	return value.(*userCredentials)
+}
+
+// userTokenSource lets a cached client pick up the latest token source given for its identity.
+type userTokenSource struct {
+	mutex       sync.Mutex
+	tokenSource oauth2.TokenSource
+}
+
+func (source *userTokenSource) Token() (*oauth2.Token, error) {
+	source.mutex.Lock()
+	tokenSource := source.tokenSource
+	source.mutex.Unlock()
+	return tokenSource.Token()
+}
+
+func (source *userTokenSource) set(tokenSource oauth2.TokenSource) {
+	source.mutex.Lock()
+	source.tokenSource = tokenSource
+	source.mutex.Unlock()
+}
+
+type userClient struct {
+	identity    string
+	client      *bigquery.Client
+	tokenSource *userTokenSource
+}
+
+// userClientCache is a least recently used cache of clients per end-user identity.
+// It is shared by all connections of a Connector.
+type userClientCache struct {
+	mutex   sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func newUserClientCache(size int) *userClientCache {
+	if size <= 0 {
+		size = DefaultUserClientCacheSize
+	}
+	return &userClientCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+func (cache *userClientCache) get(ctx context.Context, config *Config, credentials *userCredentials) (*bigquery.Client, error) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[credentials.identity]; ok {
+		cache.order.MoveToFront(element)
+		entry := element.Value.(*userClient)
+		entry.tokenSource.set(credentials.tokenSource)
+		return entry.client, nil
+	}
+
+	tokenSource := &userTokenSource{tokenSource: credentials.tokenSource}
+	opts := []option.ClientOption{option.WithTokenSource(tokenSource)}
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+	}
+	if config.JobCreationMode != "" {
+		opts = append(opts, bigquery.WithDefaultJobCreationMode(config.JobCreationMode))
+	}
+	client, err := bigquery.NewClient(ctx, config.ProjectID, opts...)
+	if err != nil {
+		return nil, err
+	}
+	client.Location = config.Location
+
+	cache.entries[credentials.identity] = cache.order.PushFront(&userClient{credentials.identity, client, tokenSource})
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Back()
+		entry := cache.order.Remove(oldest).(*userClient)
+		delete(cache.entries, entry.identity)
+		entry.client.Close()
+	}
+	return client, nil
+}
+
+func (cache *userClientCache) close() error {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	var err error
+	for element := cache.order.Front(); element != nil; element = element.Next() {
+		if closeErr := element.Value.(*userClient).client.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	cache.entries = make(map[string]*list.Element)
+	cache.order.Init()
+	return err
+}
@@ -0,0 +1,75 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestQueryContextWithUserAccessToken(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	var authorizations []string
+	dsn := newFakeBigQuery(t, func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mutex.Unlock()
+		writeQueryResponse(w, "1")
+	})
+
+	db, err := sql.Open("bigquery", dsn)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	var n int64
+	ctx := context.Background()
+	require.NoError(t, db.QueryRowContext(WithUserAccessToken(ctx, "alice-token"), "SELECT 1").Scan(&n))
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT 1").Scan(&n))
+	require.NoError(t, db.QueryRowContext(WithUserAccessToken(ctx, "bob-token"), "SELECT 1").Scan(&n))
+
+	assert.Equal(t, []string{"Bearer alice-token", "", "Bearer bob-token"}, authorizations)
+}
+
+func TestUserClientCache(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	config := &Config{ProjectID: "project", Location: "US"}
+	cache := newUserClientCache(2)
+	t.Cleanup(func() { cache.close() })
+
+	credentials := func(identity string, token string) *userCredentials {
+		return &userCredentials{identity, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})}
+	}
+
+	alice, err := cache.get(ctx, config, credentials("alice", "alice-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "US", alice.Location)
+
+	bob, err := cache.get(ctx, config, credentials("bob", "bob-1"))
+	require.NoError(t, err)
+	assert.NotSame(t, alice, bob)
+
+	// A new token source for a cached identity is used by the cached client.
+	again, err := cache.get(ctx, config, credentials("alice", "alice-2"))
+	require.NoError(t, err)
+	assert.Same(t, alice, again)
+	token, err := cache.entries["alice"].Value.(*userClient).tokenSource.Token()
+	require.NoError(t, err)
+	assert.Equal(t, "alice-2", token.AccessToken)
+
+	// bob is the least recently used identity and is evicted.
+	_, err = cache.get(ctx, config, credentials("carol", "carol-1"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, cache.order.Len())
+	assert.Contains(t, cache.entries, "alice")
+	assert.Contains(t, cache.entries, "carol")
+	assert.NotContains(t, cache.entries, "bob")
+}
@@ -5,15 +5,16 @@ go 1.23.0
 toolchain go1.24.4
 
 require (
+	cloud.google.com/go v0.121.2
 	cloud.google.com/go/bigquery v1.69.0
 	github.com/sirupsen/logrus v1.9.0
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/oauth2 v0.30.0
 	google.golang.org/api v0.238.0
 	gorm.io/gorm v1.25.5
 )
 
 require (
-	cloud.google.com/go v0.121.2 // indirect
 	cloud.google.com/go/auth v0.16.2 // indirect
 	cloud.google.com/go/auth/oauth2adapt v0.2.8 // indirect
 	cloud.google.com/go/compute/metadata v0.7.0 // indirect
@@ -46,7 +47,6 @@ require (
 	golang.org/x/exp v0.0.0-20250620022241-b7579e27df2b // indirect
 	golang.org/x/mod v0.25.0 // indirect
 	golang.org/x/net v0.41.0 // indirect
-	golang.org/x/oauth2 v0.30.0 // indirect
 	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
@@ -38,6 +38,9 @@ func (m Migrator) DropIndex(value interface{}, name string) error {
 	return errors.New("DropIndex is unsupported")
 }
 
+// HasTable uses an unqualified INFORMATION_SCHEMA view so that it resolves against the
+// default dataset of the connection, in the data project, or the dataset given to the
+// context by driver.WithDefaultDataset. HasColumn and HasConstraint do the same.
 func (m Migrator) HasTable(value interface{}) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
@@ -69,7 +72,7 @@ func (m Migrator) HasColumn(value interface{}, field string) bool {
 		}
 
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND column_name = ?",
+			"SELECT count(*) FROM `INFORMATION_SCHEMA.COLUMNS` WHERE table_name = ? AND column_name = ?",
 			stmt.Table, name,
 		).Row().Scan(&count)
 	})
@@ -81,7 +84,7 @@ func (m Migrator) HasConstraint(value interface{}, name string) bool {
 	var count int64
 	m.RunWithValue(value, func(stmt *gorm.Statement) error {
 		return m.DB.Raw(
-			"SELECT count(*) FROM INFORMATION_SCHEMA.table_constraints WHERE table_schema = CURRENT_SCHEMA() AND table_name = ? AND constraint_name = ?",
+			"SELECT count(*) FROM `INFORMATION_SCHEMA.TABLE_CONSTRAINTS` WHERE table_name = ? AND constraint_name = ?",
 			stmt.Table, name,
 		).Row().Scan(&count)
 	})